@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/zulerne/ccost/internal/display"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+func runAnomalies(args []string) int {
+	var (
+		kinds   []string
+		factor  float64
+		window  int
+		minCost float64
+		jsonOut bool
+	)
+
+	fs := flag.NewFlagSet("ccost anomalies", flag.ExitOnError)
+	fs.Usage = usage(fs, "ccost anomalies [flags]\n\nFlag days, sessions and requests whose cost is far above the project's\nrolling baseline (median and MAD of the previous --window days).\nThe MAD is scaled by 1.4826 and floored at a tenth of the median, so a\nsteady baseline doesn't flag every small rise.")
+	qf := addQueryFlags(fs)
+	fs.StringSliceVar(&kinds, "kind", nil, "what to check: day, session, request (default all)")
+	fs.Float64Var(&factor, "factor", 3, "flag costs above median + factor × spread, where spread is 1.4826 × MAD but at least 10% of the median")
+	fs.IntVar(&window, "window", 14, "days of history forming the baseline")
+	fs.Float64Var(&minCost, "min-cost", 1, "ignore costs below this amount in USD")
+	fs.BoolVar(&jsonOut, "json", false, "output as JSON")
+	_ = fs.Parse(args)
+
+	opts := report.AnomalyOptions{Factor: factor, Window: window, MinCost: minCost}
+	for _, k := range kinds {
+		kind, err := report.ParseAnomalyKind(k)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --kind: %v\n", err)
+			return 1
+		}
+		opts.Kinds = append(opts.Kinds, kind)
+	}
+	if factor <= 0 || window < 1 {
+		fmt.Fprintln(os.Stderr, "invalid --factor or --window: must be positive")
+		return 1
+	}
+
+	q, err := qf.build(true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Load the window before the range too, so the first days have a baseline.
+	if !q.opts.Since.IsZero() {
+		opts.From = q.opts.Since.Format("2006-01-02")
+		q.opts.Since = q.opts.Since.AddDate(0, 0, -window)
+	}
+
+	records, _, err := q.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(os.Stderr, "no records found")
+		return 0
+	}
+
+	anomalies := report.FindAnomalies(records, opts)
+
+	if jsonOut {
+		if err := display.AnomaliesJSON(os.Stdout, anomalies); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(anomalies) == 0 {
+		fmt.Fprintln(os.Stderr, "no anomalies found")
+		return 0
+	}
+	title := fmt.Sprintf("Anomalies · > median + %g × max(1.48 MAD, median/10) over %d days", factor, window)
+	if q.title != "" {
+		title += " · " + q.title
+	}
+	display.AnomaliesTable(os.Stdout, anomalies, title)
+	return 0
+}
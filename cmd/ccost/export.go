@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"github.com/zulerne/ccost/internal/bundle"
+	"github.com/zulerne/ccost/internal/store"
+)
+
+func runExport(args []string) int {
+	var (
+		bundleOut bool
+		user      string
+		output    string
+		dbPath    string
+	)
+
+	fs := flag.NewFlagSet("ccost export", flag.ExitOnError)
+	fs.Usage = usage(fs, "ccost export --bundle [flags]\n       ccost export --sqlite FILE [flags]\n\nWrite usage records for sharing or further analysis (all history by default).")
+	qf := addQueryFlags(fs)
+	fs.BoolVar(&bundleOut, "bundle", false, "write a portable JSON snapshot for ccost merge")
+	fs.StringVar(&user, "user", "", "label the snapshot's records with a user name (for --by user after merging)")
+	fs.StringVarP(&output, "output", "o", "", "write the bundle to this file instead of stdout")
+	fs.StringVar(&dbPath, "sqlite", "", "append records, sessions, projects and prices to this SQLite database")
+	_ = fs.Parse(args)
+
+	if bundleOut == (dbPath != "") {
+		fmt.Fprintln(os.Stderr, "choose one export format: --bundle or --sqlite FILE")
+		return 1
+	}
+	if (output != "" || user != "") && !bundleOut {
+		fmt.Fprintln(os.Stderr, "--output and --user require --bundle")
+		return 1
+	}
+
+	q, err := qf.build(false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	records, sessions, err := q.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(os.Stderr, "no records found")
+		return 0
+	}
+
+	if dbPath != "" {
+		st, err := store.Write(dbPath, records, sessions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error writing database: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d new records, %d updated, %d already present, %d session days updated\n",
+			dbPath, st.Records, st.Updated, st.Skipped, st.Sessions)
+		return 0
+	}
+
+	b := bundle.New(records, sessions, user, time.Now())
+	if err := writeOutput(output, func(w io.Writer) error { return bundle.Write(w, b) }); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing bundle: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeOutput calls write with path opened for writing, or with stdout when
+// path is empty.
+func writeOutput(path string, write func(io.Writer) error) (err error) {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, f.Close()) }()
+	return write(f)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/zulerne/ccost/internal/display"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+func runHeatmap(args []string) int {
+	var (
+		metricStr string
+		exact     bool
+		ascii     bool
+		jsonOut   bool
+	)
+
+	fs := flag.NewFlagSet("ccost heatmap", flag.ExitOnError)
+	fs.Usage = usage(fs, "ccost heatmap [flags]\n\nShow usage by weekday and hour of day (in --tz, default local).")
+	qf := addQueryFlags(fs)
+	fs.StringVar(&metricStr, "metric", "cost", "cell value: cost or tokens")
+	fs.BoolVarP(&exact, "exact", "e", false, "show exact token counts instead of compact (K/M)")
+	fs.BoolVar(&ascii, "ascii", false, "use plain ASCII shading (default when the locale is not UTF-8)")
+	fs.BoolVar(&jsonOut, "json", false, "output as JSON")
+	_ = fs.Parse(args)
+
+	metric, err := report.ParseMetric(metricStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --metric: %v\n", err)
+		return 1
+	}
+
+	q, err := qf.build(true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	records, _, err := q.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(os.Stderr, "no records found")
+		return 0
+	}
+
+	h := report.ByHour(records, metric)
+
+	if jsonOut {
+		if err := display.HeatmapJSON(os.Stdout, &h); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing JSON: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	title := "Heatmap · " + string(metric)
+	if q.title != "" {
+		title += " · " + q.title
+	}
+	display.HeatmapGrid(os.Stdout, &h, title, exact, ascii || !unicodeLocale())
+	return 0
+}
@@ -3,126 +3,410 @@ package main
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	flag "github.com/spf13/pflag"
 	"github.com/zulerne/ccost/internal/display"
-	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/pricing"
 	"github.com/zulerne/ccost/internal/report"
 )
 
 var version = "dev"
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "tui":
+			os.Exit(runTUI(os.Args[2:]))
+		case "heatmap":
+			os.Exit(runHeatmap(os.Args[2:]))
+		case "records":
+			os.Exit(runRecords(os.Args[2:]))
+		case "anomalies":
+			os.Exit(runAnomalies(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "merge":
+			os.Exit(runReport(os.Args[2:], true))
+		}
+	}
+	os.Exit(runReport(os.Args[1:], false))
+}
+
+// usage returns a FlagSet usage func that prints a synopsis before the flags.
+func usage(fs *flag.FlagSet, synopsis string) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\nFlags:\n", synopsis)
+		fs.PrintDefaults()
+	}
+}
+
+// runReport prints a usage report of the local logs, or with merge set, of
+// the bundle files given as arguments.
+func runReport(args []string, merge bool) int {
 	var (
-		sinceStr   string
-		untilStr   string
-		project    string
 		byProject  bool
+		byBranch   bool
+		byTool     bool
+		groupBy    string
+		groupDims  []string
+		pivot      string
+		metricStr  string
+		csvOut     bool
+		format     string
+		cumulative bool
+		rates      bool
 		models     bool
 		exact      bool
+		chart      bool
+		cache      bool
+		agents     bool
+		sortStr    string
+		desc       bool
+		top        int
+		plan       string
+		planFee    float64
+		users      int
+		ascii      bool
 		jsonOut    bool
 		versionOut bool
 	)
 
-	flag.StringVarP(&sinceStr, "since", "s", "", "start date (YYYY-MM-DD)")
-	flag.StringVarP(&untilStr, "until", "u", "", "end date (YYYY-MM-DD), inclusive")
-	flag.StringVarP(&project, "project", "p", "", "filter by project name (substring)")
-	flag.BoolVarP(&byProject, "by-project", "b", false, "group by project instead of date")
-	flag.BoolVarP(&models, "models", "m", false, "show per-model breakdown")
-	flag.BoolVarP(&exact, "exact", "e", false, "show exact token counts instead of compact (K/M)")
-	flag.BoolVar(&jsonOut, "json", false, "output as JSON")
-	flag.BoolVarP(&versionOut, "version", "v", false, "print version and exit")
-	flag.Parse()
+	name, synopsis := "ccost", "ccost [flags]\n       ccost tui [flags]\n       ccost heatmap [flags]\n       ccost records [flags]\n       ccost anomalies [flags]\n       ccost export --bundle [flags]\n       ccost merge [flags] BUNDLE..."
+	if merge {
+		name, synopsis = "ccost merge", "ccost merge [flags] BUNDLE...\n\nCombine bundles from ccost export --bundle into one report (all history by default)."
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = usage(fs, synopsis)
+	qf := addQueryFlags(fs)
+	fs.BoolVarP(&byProject, "by-project", "b", false, "group by project instead of date")
+	fs.BoolVar(&byBranch, "by-branch", false, "group by git branch (same as --by branch)")
+	fs.BoolVar(&byTool, "by-tool", false, "attribute cost to the tools each response invoked (same as --by tool)")
+	fs.StringVar(&groupBy, "by", "date", "group by date, project, repo, branch, model, session, agent, tool or user")
+	fs.StringVar(&sortStr, "sort", "key", "order rows by key, cost, tokens or duration")
+	fs.BoolVar(&desc, "desc", false, "sort in descending order")
+	fs.IntVarP(&top, "top", "n", 0, "keep the N largest rows (by --sort, or cost) and fold the rest into \"(other)\"")
+	fs.StringSliceVar(&groupDims, "group", nil, "nested grouping, outermost first, e.g. project,date,model")
+	fs.StringVar(&pivot, "pivot", "", "matrix of ROWS,COLS dimensions, e.g. date,model")
+	fs.StringVar(&metricStr, "metric", "cost", "pivot cell value: cost or tokens")
+	fs.BoolVarP(&models, "models", "m", false, "split rows by model (a breakdown, not a filter; see --model)")
+	fs.BoolVarP(&exact, "exact", "e", false, "show exact token counts instead of compact (K/M)")
+	fs.BoolVar(&cache, "cache", false, "show cache hit ratio, savings and net ROI columns")
+	fs.BoolVar(&rates, "rates", false, "add cost per active hour, tokens per minute and output/input ratio columns")
+	fs.BoolVar(&cumulative, "cumulative", false, "add running-total cost and token columns (date reports in date order only)")
+	fs.BoolVar(&agents, "split-agents", false, "split cost between the main session and subagents (Task tool)")
+	fs.StringVar(&plan, "plan", "", "compare API-equivalent cost with a subscription: "+strings.Join(pricing.PlanNames, ", "))
+	fs.Float64Var(&planFee, "plan-fee", 0, "custom monthly plan fee per user in USD (implies --plan, overrides its fee)")
+	fs.IntVar(&users, "users", 1, "number of subscribed users the plan fee is paid for (merge counts the bundles' users instead)")
+	fs.BoolVar(&chart, "chart", false, "draw a cost bar chart and daily sparkline under the table")
+	fs.BoolVar(&ascii, "ascii", false, "use plain ASCII for charts (default when the locale is not UTF-8)")
+	fs.BoolVar(&jsonOut, "json", false, "output as JSON")
+	fs.BoolVar(&csvOut, "csv", false, "output the pivot as CSV (requires --pivot)")
+	fs.StringVar(&format, "format", "", "output format: table, json, csv (pivot only) or ndjson (one row per line)")
+	fs.BoolVarP(&versionOut, "version", "v", false, "print version and exit")
+	_ = fs.Parse(args)
 
 	if versionOut {
 		fmt.Println("ccost " + version)
-		os.Exit(0)
+		return 0
 	}
 
-	weeklyMode := sinceStr == "" && untilStr == ""
+	dim, err := report.ParseDimension(groupBy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --by: %v\n", err)
+		return 1
+	}
+	shortcuts := map[report.Dimension]bool{
+		report.Project: byProject,
+		report.Branch:  byBranch,
+		report.Tool:    byTool,
+	}
+	for want, set := range shortcuts {
+		if !set {
+			continue
+		}
+		if fs.Changed("by") && dim != want || countTrue(byProject, byBranch, byTool) > 1 {
+			fmt.Fprintln(os.Stderr, "conflicting grouping flags: use only one of --by, --by-project, --by-branch, --by-tool")
+			return 1
+		}
+		dim = want
+	}
 
-	opts := parser.Options{
-		Project: project,
+	nested := make([]report.Dimension, 0, len(groupDims))
+	for _, name := range groupDims {
+		var d report.Dimension
+		if d, err = report.ParseDimension(name); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --group: %v\n", err)
+			return 1
+		}
+		if slices.Contains(nested, d) {
+			fmt.Fprintf(os.Stderr, "invalid --group: %s given twice\n", d)
+			return 1
+		}
+		nested = append(nested, d)
+	}
+	if len(nested) > 0 {
+		if fs.Changed("by") || countTrue(byProject, byBranch, byTool) > 0 {
+			fmt.Fprintln(os.Stderr, "conflicting grouping flags: --group replaces --by, --by-project, --by-branch, --by-tool")
+			return 1
+		}
+		if models && !slices.Contains(nested, report.Model) {
+			nested = append(nested, report.Model)
+		}
+		dim = nested[0]
 	}
 
-	if weeklyMode {
-		now := time.Now()
-		sevenDaysAgo := now.AddDate(0, 0, -6)
-		opts.Since = time.Date(sevenDaysAgo.Year(), sevenDaysAgo.Month(), sevenDaysAgo.Day(), 0, 0, 0, 0, now.Location())
+	ndjson, err := applyFormat(format, &jsonOut, &csvOut)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
 
-	if sinceStr != "" {
-		t, err := time.ParseInLocation("2006-01-02", sinceStr, time.Local)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "invalid --since date: %v\n", err)
-			os.Exit(1)
+	var pivotRows, pivotCols report.Dimension
+	if pivot != "" {
+		if pivotRows, pivotCols, err = report.ParsePivot(pivot); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --pivot: %v\n", err)
+			return 1
 		}
-		opts.Since = t
+		if len(nested) > 0 || fs.Changed("by") || countTrue(byProject, byBranch, byTool, models) > 0 {
+			fmt.Fprintln(os.Stderr, "conflicting grouping flags: --pivot replaces --by, --group and --models")
+			return 1
+		}
+		if ndjson {
+			fmt.Fprintln(os.Stderr, "--format ndjson is not supported with --pivot")
+			return 1
+		}
+	} else if csvOut {
+		fmt.Fprintln(os.Stderr, "--csv requires --pivot")
+		return 1
+	}
+	metric, err := report.ParseMetric(metricStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --metric: %v\n", err)
+		return 1
+	}
+	if jsonOut && csvOut {
+		fmt.Fprintln(os.Stderr, "conflicting output flags: use only one of --json, --csv")
+		return 1
 	}
 
-	if untilStr != "" {
-		t, err := time.ParseInLocation("2006-01-02", untilStr, time.Local)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "invalid --until date: %v\n", err)
-			os.Exit(1)
+	if cumulative && (dim != report.Date || len(nested) > 1 || pivot != "") {
+		fmt.Fprintln(os.Stderr, "--cumulative requires grouping by date")
+		return 1
+	}
+	// Running totals only read correctly top to bottom in date order, and
+	// "(other)" would have none.
+	if cumulative && (fs.Changed("sort") || desc || top > 0) {
+		fmt.Fprintln(os.Stderr, "--cumulative keeps rows in date order: it can't be combined with --sort, --desc or --top")
+		return 1
+	}
+
+	sortField, err := report.ParseSortField(sortStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --sort: %v\n", err)
+		return 1
+	}
+	if top < 0 {
+		fmt.Fprintln(os.Stderr, "invalid --top: must not be negative")
+		return 1
+	}
+
+	if planFee < 0 {
+		fmt.Fprintln(os.Stderr, "invalid --plan-fee: must not be negative")
+		return 1
+	}
+	fee := planFee
+	if plan != "" {
+		f, ok := pricing.PlanFee(plan)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "invalid --plan %q (want one of %s)\n", plan, strings.Join(pricing.PlanNames, ", "))
+			return 1
 		}
-		// Make until inclusive: set to end of that day.
-		opts.Until = t.Add(24*time.Hour - time.Nanosecond)
+		if planFee == 0 {
+			fee = f
+		}
+	}
+	if users < 1 {
+		fmt.Fprintln(os.Stderr, "invalid --users: must be at least 1")
+		return 1
 	}
 
-	records, sessions, warnings, err := parser.Parse(opts)
+	if merge && fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "ccost merge: no bundle files given")
+		return 1
+	}
+
+	q, err := qf.build(!merge)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if merge {
+		q.bundles = fs.Args()
+		q.title = strings.TrimSuffix(fmt.Sprintf("Merged · %d bundles · %s", fs.NArg(), q.title), " · ")
 	}
 
-	for _, w := range warnings {
-		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	records, sessions, err := q.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
 	}
 
 	if len(records) == 0 {
 		fmt.Fprintln(os.Stderr, "no records found")
-		os.Exit(0)
+		return 0
+	}
+
+	if pivot != "" {
+		p := report.PivotBy(pivotRows, pivotCols, metric, records)
+		return writePivot(&p, q.title, exact, jsonOut, csvOut)
 	}
 
 	var rpt report.Report
-	keyHeader := "Date"
-	if byProject {
-		keyHeader = "Project"
+	if len(nested) > 1 {
+		rpt = report.Nested(nested, records, sessions)
+	} else {
+		rpt = report.By(dim, records, sessions, models)
 	}
-	var title string
+	if cumulative {
+		rpt.Cumulate()
+	}
+	if sortField != report.SortKey || desc {
+		rpt.Sort(sortField, desc)
+	}
+	rpt.Top(top, sortField)
+	rpt.ComputeRates()
+	if fee > 0 {
+		// Without --until the range runs to today, so the current month
+		// is compared against the fee for the days so far.
+		until := q.opts.Until
+		if until.IsZero() {
+			until = time.Now().In(q.loc)
+		}
+		rpt.Plan = report.ComparePlan(records, fee, users, q.opts.Since, until)
+	}
+
 	switch {
-	case weeklyMode:
-		now := time.Now()
-		title = fmt.Sprintf("Weekly · %s – %s", opts.Since.Format("Jan 02"), now.Format("Jan 02"))
-	case sinceStr != "" && untilStr != "":
-		title = fmt.Sprintf("Range · %s – %s", opts.Since.Format("Jan 02"), opts.Until.Format("Jan 02"))
-	case sinceStr != "":
-		title = "Since · " + opts.Since.Format("Jan 02")
-	case untilStr != "":
-		title = "Until · " + opts.Until.Format("Jan 02")
-	}
-	if byProject {
-		if models {
-			rpt = report.ByProjectDetailed(records, sessions)
-		} else {
-			rpt = report.ByProject(records, sessions)
+	case jsonOut:
+		if err := display.JSON(os.Stdout, &rpt); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing JSON: %v\n", err)
+			return 1
 		}
-	} else {
-		if models {
-			rpt = report.ByDateDetailed(records, sessions)
-		} else {
-			rpt = report.ByDate(records, sessions)
+	case ndjson:
+		if err := display.RowsNDJSON(os.Stdout, &rpt); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing NDJSON: %v\n", err)
+			return 1
+		}
+	default:
+		display.Table(os.Stdout, &rpt, display.TableOptions{
+			KeyHeader:  keyHeader(dim, nested),
+			Title:      q.title,
+			Exact:      exact,
+			Cache:      cache,
+			Agents:     agents,
+			Calls:      dim == report.Tool,
+			Active:     fs.Changed("idle"),
+			Cumulative: cumulative,
+			Rates:      rates,
+		})
+		if len(rpt.Plan) > 0 {
+			fmt.Println()
+			display.PlanTable(os.Stdout, rpt.Plan, planTitle(plan, fee, rpt.Plan[0].Users))
+		}
+		if chart {
+			width := terminalWidth()
+			ascii = ascii || !unicodeLocale()
+			fmt.Println()
+			display.Bars(os.Stdout, &rpt, width, ascii)
+			daily := report.ByDate(records, sessions)
+			fmt.Println()
+			display.Sparkline(os.Stdout, &daily, width, ascii)
 		}
 	}
+	return 0
+}
 
-	if jsonOut {
-		if err := display.JSON(os.Stdout, &rpt); err != nil {
+// planTitle describes the plan being compared, e.g. "Plan · max5 × 3 users".
+func planTitle(plan string, fee float64, users int) string {
+	name := plan
+	if name == "" {
+		name = fmt.Sprintf("$%.2f/month", fee)
+	}
+	title := "Plan · " + name
+	if users > 1 {
+		title += fmt.Sprintf(" × %d users", users)
+	}
+	return title
+}
+
+// applyFormat maps --format onto the --json and --csv shorthands and
+// reports whether NDJSON was chosen. An empty format leaves them as given.
+func applyFormat(format string, jsonOut, csvOut *bool) (bool, error) {
+	if format == "" {
+		return false, nil
+	}
+	if *jsonOut && format != "json" || *csvOut && format != "csv" {
+		return false, fmt.Errorf("conflicting output flags: --format %s with --json or --csv", format)
+	}
+	switch format {
+	case "table":
+	case "json":
+		*jsonOut = true
+	case "csv":
+		*csvOut = true
+	case "ndjson":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid --format %q (want table, json, csv or ndjson)", format)
+	}
+	return false, nil
+}
+
+// countTrue returns how many of flags are set.
+func countTrue(flags ...bool) int {
+	n := 0
+	for _, f := range flags {
+		if f {
+			n++
+		}
+	}
+	return n
+}
+
+// keyHeader names the key column: the dimension, or every level of a nested
+// grouping, e.g. "Project › Date".
+func keyHeader(dim report.Dimension, nested []report.Dimension) string {
+	if len(nested) < 2 {
+		return dim.Header()
+	}
+	headers := make([]string, len(nested))
+	for i, d := range nested {
+		headers[i] = d.Header()
+	}
+	return strings.Join(headers, " › ")
+}
+
+// writePivot renders a pivot as a table, JSON or CSV.
+func writePivot(p *report.Pivot, title string, exact, jsonOut, csvOut bool) int {
+	switch {
+	case jsonOut:
+		if err := display.PivotJSON(os.Stdout, p); err != nil {
 			fmt.Fprintf(os.Stderr, "error writing JSON: %v\n", err)
-			os.Exit(1)
+			return 1
 		}
-	} else {
-		display.Table(os.Stdout, &rpt, keyHeader, exact, title)
+	case csvOut:
+		if err := display.PivotCSV(os.Stdout, p); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing CSV: %v\n", err)
+			return 1
+		}
+	default:
+		t := "Pivot · " + string(p.Metric)
+		if title != "" {
+			t += " · " + title
+		}
+		display.PivotTable(os.Stdout, p, t, exact)
 	}
+	return 0
 }
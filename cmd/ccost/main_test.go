@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestCumulativeFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // no logs: accepted flags end with "no records found"
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--cumulative"}, 0},
+		{[]string{"--cumulative", "--models"}, 0},
+		{[]string{"--cumulative", "--sort", "cost"}, 1},
+		{[]string{"--cumulative", "--desc"}, 1},
+		{[]string{"--cumulative", "--top", "3"}, 1},
+		{[]string{"--cumulative", "--by-project"}, 1},
+	}
+	for _, tt := range tests {
+		if got := runReport(tt.args, false); got != tt.want {
+			t.Errorf("runReport(%q) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRecordsNDJSONFlags(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // no logs: accepted flags end with "no records found"
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--format", "ndjson"}, 0},
+		{[]string{"--format", "ndjson", "--sort", "time"}, 0},
+		{[]string{"--format", "ndjson", "--sort", "cost"}, 1},
+		{[]string{"--format", "ndjson", "--top", "3"}, 1},
+		{[]string{"--format", "json", "--sort", "cost", "--top", "3"}, 0},
+	}
+	for _, tt := range tests {
+		if got := runRecords(tt.args); got != tt.want {
+			t.Errorf("runRecords(%q) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"github.com/zulerne/ccost/internal/bundle"
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/redact"
+)
+
+// queryFlags holds the date range and filter flags shared by every command
+// that reads session logs.
+type queryFlags struct {
+	since     string
+	until     string
+	projects  []string
+	exclProj  []string
+	projRegex []string
+	modelIncl []string
+	modelRe   []string
+	modelExcl []string
+	branch    string
+	aliases   []string
+	tz        string
+	idle      time.Duration
+	redact    string
+	salt      string
+	sessions  bool
+}
+
+func addQueryFlags(fs *flag.FlagSet) *queryFlags {
+	q := &queryFlags{}
+	fs.StringVarP(&q.since, "since", "s", "", "start date (YYYY-MM-DD)")
+	fs.StringVarP(&q.until, "until", "u", "", "end date (YYYY-MM-DD), inclusive")
+	fs.StringArrayVarP(&q.projects, "project", "p", nil, "filter by project name (substring, repeatable)")
+	fs.StringArrayVar(&q.exclProj, "exclude-project", nil, "exclude projects by name (substring, repeatable)")
+	fs.StringArrayVar(&q.projRegex, "project-regex", nil, "filter by project name (regular expression, repeatable)")
+	fs.StringArrayVar(&q.modelIncl, "model", nil, "only count usage of models matching this name, session time included (substring, repeatable; for a breakdown use --models)")
+	fs.StringArrayVar(&q.modelRe, "model-regex", nil, "only count usage of models matching this name (regular expression, repeatable)")
+	fs.StringArrayVar(&q.modelExcl, "exclude-model", nil, "exclude models by name, with sessions that used only them (substring, repeatable)")
+	fs.StringVar(&q.branch, "branch", "", "filter by git branch name (substring)")
+	fs.StringArrayVar(&q.aliases, "alias", nil, "merge projects: PATTERN=NAME, PATTERN is a path glob or re:REGEX (repeatable)")
+	fs.StringVar(&q.tz, "tz", "", "time zone for day boundaries, e.g. UTC or Europe/Berlin (default local)")
+	fs.DurationVar(&q.idle, "idle", parser.DefaultIdle, "gaps between entries longer than this don't count as active session time; shows an Active column in tables")
+	fs.StringVar(&q.redact, "redact", "", "replace project, repo, branch and path names with pseudonyms: hash or alias (needs a salt); session IDs are dropped")
+	fs.StringVar(&q.salt, "redact-salt", "", "secret that keys --redact pseudonyms (default $CCOST_REDACT_SALT, required); share it to merge redacted bundles")
+	fs.BoolVar(&q.sessions, "redact-keep-sessions", false, "with --redact, keep session IDs as salted tokens (linkable across exports) for --by session and session anomalies")
+	return q
+}
+
+// query is a validated set of parser options plus a human-readable title
+// describing the selected period.
+type query struct {
+	opts    parser.Options
+	loc     *time.Location
+	zoned   bool // --tz was given
+	title   string
+	bundles []string // read these bundle files instead of the local logs
+	redact  *redact.Redactor
+}
+
+// build validates the flags. When no dates are given and weeklyDefault is
+// true, the range defaults to the last 7 days including today.
+func (q *queryFlags) build(weeklyDefault bool) (*query, error) {
+	loc := time.Local
+	if q.tz != "" {
+		l, err := time.LoadLocation(q.tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --tz: %w", err)
+		}
+		loc = l
+	}
+
+	opts := parser.Options{
+		Project:  parser.Filter{Include: q.projects, Exclude: q.exclProj},
+		Model:    parser.Filter{Include: q.modelIncl, Exclude: q.modelExcl},
+		Branch:   q.branch,
+		Location: loc,
+		Idle:     q.idle,
+	}
+
+	for _, expr := range q.projRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --project-regex: %w", err)
+		}
+		opts.Project.Regex = append(opts.Project.Regex, re)
+	}
+	for _, expr := range q.modelRe {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --model-regex: %w", err)
+		}
+		opts.Model.Regex = append(opts.Model.Regex, re)
+	}
+
+	for _, rule := range q.aliases {
+		a, err := parser.ParseAlias(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --alias: %w", err)
+		}
+		opts.Aliases = append(opts.Aliases, a)
+	}
+
+	if q.idle <= 0 {
+		return nil, errors.New("invalid --idle: must be positive")
+	}
+
+	if q.sessions && q.redact == "" {
+		return nil, errors.New("--redact-keep-sessions requires --redact")
+	}
+	var redactor *redact.Redactor
+	if q.redact != "" {
+		mode, err := redact.ParseMode(q.redact)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --redact: %w", err)
+		}
+		// Without a salt, pseudonyms of common names can be recovered by
+		// hashing guesses.
+		salt := cmp.Or(q.salt, os.Getenv("CCOST_REDACT_SALT"))
+		if salt == "" {
+			return nil, errors.New("--redact needs a secret salt: set --redact-salt or CCOST_REDACT_SALT")
+		}
+		redactor = redact.New(mode, salt, q.sessions)
+	}
+
+	weeklyMode := weeklyDefault && q.since == "" && q.until == ""
+	now := time.Now().In(loc)
+
+	if weeklyMode {
+		sevenDaysAgo := now.AddDate(0, 0, -6)
+		opts.Since = time.Date(sevenDaysAgo.Year(), sevenDaysAgo.Month(), sevenDaysAgo.Day(), 0, 0, 0, 0, loc)
+	}
+
+	if q.since != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.since, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --since date: %w", err)
+		}
+		opts.Since = t
+	}
+
+	if q.until != "" {
+		t, err := time.ParseInLocation("2006-01-02", q.until, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --until date: %w", err)
+		}
+		// Make until inclusive: set to end of that day.
+		opts.Until = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	var title string
+	switch {
+	case weeklyMode:
+		title = fmt.Sprintf("Weekly · %s – %s", opts.Since.Format("Jan 02"), now.Format("Jan 02"))
+	case q.since != "" && q.until != "":
+		title = fmt.Sprintf("Range · %s – %s", opts.Since.Format("Jan 02"), opts.Until.Format("Jan 02"))
+	case q.since != "":
+		title = "Since · " + opts.Since.Format("Jan 02")
+	case q.until != "":
+		title = "Until · " + opts.Until.Format("Jan 02")
+	}
+	if q.tz != "" {
+		title += " · " + loc.String()
+	}
+
+	return &query{opts: opts, loc: loc, zoned: q.tz != "", title: title, redact: redactor}, nil
+}
+
+// load parses session logs and prints parser warnings to stderr. When
+// bundles are set, it merges them instead and applies the filters to the
+// result. Names are redacted last, so filters match the real names and
+// every output sees the same pseudonyms.
+func (q *query) load() ([]parser.Record, []parser.Session, error) {
+	var records []parser.Record
+	var sessions []parser.Session
+	if len(q.bundles) > 0 {
+		var err error
+		if records, sessions, err = q.loadBundles(); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var warnings []string
+		var err error
+		if records, sessions, warnings, err = parser.Parse(&q.opts); err != nil {
+			return nil, nil, err
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+	}
+	if q.redact != nil {
+		q.redact.Apply(records, sessions)
+	}
+	return records, sessions, nil
+}
+
+// stream parses the session logs like load, but passes each file's records
+// to fn as soon as the file is parsed, then prints parser warnings.
+func (q *query) stream(fn func([]parser.Record) error) error {
+	warnings, err := parser.Stream(&q.opts, func(records []parser.Record) error {
+		if q.redact != nil {
+			q.redact.Apply(records, nil)
+		}
+		return fn(records)
+	})
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	return nil
+}
+
+// loadBundles merges q.bundles. A bundle exported without --user is labelled
+// with its file name. Session days can't be moved to another time zone, so
+// each bundle keeps the zone it was exported in, and --tz must match it.
+func (q *query) loadBundles() ([]parser.Record, []parser.Session, error) {
+	bundles := make([]*bundle.Bundle, 0, len(q.bundles))
+	for _, path := range q.bundles {
+		b, err := bundle.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if b.User == "" {
+			b.User = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if q.zoned && !b.InZone(q.loc) {
+			return nil, nil, fmt.Errorf("%s: exported in another time zone than --tz %s; drop --tz or re-export with it", path, q.loc)
+		}
+		bundles = append(bundles, b)
+	}
+	records, sessions := bundle.Merge(bundles)
+	opts := q.opts
+	if !q.zoned {
+		opts.Location = nil
+	}
+	records, sessions = parser.Select(records, sessions, &opts)
+	return records, sessions, nil
+}
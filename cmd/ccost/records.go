@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/zulerne/ccost/internal/display"
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+func runRecords(args []string) int {
+	var (
+		sortStr string
+		top     int
+		exact   bool
+		jsonOut bool
+		csvOut  bool
+		format  string
+	)
+
+	fs := flag.NewFlagSet("ccost records", flag.ExitOnError)
+	fs.Usage = usage(fs, "ccost records [flags]\n\nList individual requests (deduplicated assistant messages).")
+	qf := addQueryFlags(fs)
+	fs.StringVar(&sortStr, "sort", "time", "order: time (oldest first), cost or tokens (largest first)")
+	fs.IntVarP(&top, "top", "n", 0, "show only the first N records after sorting")
+	fs.BoolVarP(&exact, "exact", "e", false, "show exact token counts instead of compact (K/M)")
+	fs.BoolVar(&jsonOut, "json", false, "output as JSON")
+	fs.BoolVar(&csvOut, "csv", false, "output as CSV")
+	fs.StringVar(&format, "format", "", "output format: table, json, csv or ndjson (one record per line, written as each log file is parsed; oldest first within a file, needs --sort time and no --top)")
+	_ = fs.Parse(args)
+
+	order, err := report.ParseRecordOrder(sortStr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --sort: %v\n", err)
+		return 1
+	}
+	if top < 0 {
+		fmt.Fprintln(os.Stderr, "invalid --top: must not be negative")
+		return 1
+	}
+	ndjson, err := applyFormat(format, &jsonOut, &csvOut)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if jsonOut && csvOut {
+		fmt.Fprintln(os.Stderr, "conflicting output flags: use only one of --json, --csv")
+		return 1
+	}
+	if ndjson && (order != report.ByTime || top > 0) {
+		fmt.Fprintln(os.Stderr, "--format ndjson streams records as they are parsed: use it with --sort time and without --top")
+		return 1
+	}
+
+	q, err := qf.build(true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if ndjson {
+		return streamRecords(q)
+	}
+
+	records, _, err := q.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(os.Stderr, "no records found")
+		return 0
+	}
+
+	report.SortRecords(records, order)
+	if top > 0 && top < len(records) {
+		records = records[:top]
+	}
+
+	switch {
+	case jsonOut:
+		if err := display.RecordsJSON(os.Stdout, records); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing JSON: %v\n", err)
+			return 1
+		}
+	case csvOut:
+		if err := display.RecordsCSV(os.Stdout, records); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing CSV: %v\n", err)
+			return 1
+		}
+	default:
+		title := "Records"
+		if q.title != "" {
+			title += " · " + q.title
+		}
+		display.RecordsTable(os.Stdout, records, title, exact)
+	}
+	return 0
+}
+
+// streamRecords writes records as NDJSON file by file while the logs are
+// parsed, so a pipe sees the first records without waiting for the rest.
+func streamRecords(q *query) int {
+	var n int
+	err := q.stream(func(records []parser.Record) error {
+		n += len(records)
+		return display.RecordsNDJSON(os.Stdout, records)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing NDJSON: %v\n", err)
+		return 1
+	}
+	if n == 0 {
+		fmt.Fprintln(os.Stderr, "no records found")
+	}
+	return 0
+}
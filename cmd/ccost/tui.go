@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flag "github.com/spf13/pflag"
+	"github.com/zulerne/ccost/internal/tui"
+)
+
+func runTUI(args []string) int {
+	var exact bool
+
+	fs := flag.NewFlagSet("ccost tui", flag.ExitOnError)
+	fs.Usage = usage(fs, "ccost tui [flags]\n\nBrowse usage interactively. Without --since/--until all history is loaded.")
+	qf := addQueryFlags(fs)
+	fs.BoolVarP(&exact, "exact", "e", false, "start with exact token counts instead of compact (K/M)")
+	_ = fs.Parse(args)
+
+	q, err := qf.build(false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	records, sessions, err := q.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintln(os.Stderr, "no records found")
+		return 0
+	}
+
+	if err := tui.Run(records, sessions, tui.Options{Title: q.title, Location: q.loc, Exact: exact}); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,249 @@
+// Package bundle reads and writes portable usage snapshots, so records
+// exported on several machines can be merged into team reports.
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// Format identifies a ccost bundle; Version is the newest layout this build
+// reads and the one it writes.
+const (
+	Format  = "ccost-bundle"
+	Version = 1
+)
+
+// Bundle is a snapshot of deduplicated records and per-day sessions.
+type Bundle struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	User     string    `json:"user,omitempty"`
+	Records  []Record  `json:"records"`
+	Sessions []Session `json:"sessions"`
+}
+
+// Record is the serialized form of parser.Record. Times keep the offset they
+// were exported with.
+type Record struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Model      string    `json:"model"`
+	Project    string    `json:"project"`
+	CWD        string    `json:"cwd,omitempty"`
+	Repo       string    `json:"repo,omitempty"`
+	Branch     string    `json:"branch,omitempty"`
+	Session    string    `json:"session"`
+	AgentID    string    `json:"agent_id,omitempty"`
+	Tools      []string  `json:"tools,omitempty"`
+	Input      int       `json:"input_tokens"`
+	Output     int       `json:"output_tokens"`
+	CacheWrite int       `json:"cache_write_tokens"`
+	CacheRead  int       `json:"cache_read_tokens"`
+
+	WebSearches int `json:"web_search_requests,omitempty"`
+	WebFetches  int `json:"web_fetch_requests,omitempty"`
+}
+
+// Session is the serialized form of parser.Session.
+type Session struct {
+	ID              string `json:"id"`
+	Date            string `json:"date"`
+	Project         string `json:"project"`
+	CWD             string `json:"cwd,omitempty"`
+	Repo            string `json:"repo,omitempty"`
+	Branch          string `json:"branch,omitempty"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	ActiveSeconds   int64  `json:"active_seconds"`
+}
+
+// New builds a bundle labelled user from parsed records and sessions.
+func New(records []parser.Record, sessions []parser.Session, user string, created time.Time) *Bundle {
+	b := &Bundle{
+		Format:   Format,
+		Version:  Version,
+		Created:  created,
+		User:     user,
+		Records:  make([]Record, len(records)),
+		Sessions: make([]Session, len(sessions)),
+	}
+	for i := range records {
+		r := &records[i]
+		b.Records[i] = Record{
+			ID:          r.ID,
+			Time:        r.Time,
+			Model:       r.Model,
+			Project:     r.Project,
+			CWD:         r.CWD,
+			Repo:        r.Repo,
+			Branch:      r.Branch,
+			Session:     r.Session,
+			AgentID:     r.AgentID,
+			Tools:       r.Tools,
+			Input:       r.Input,
+			Output:      r.Output,
+			CacheWrite:  r.CacheWrite,
+			CacheRead:   r.CacheRead,
+			WebSearches: r.WebSearches,
+			WebFetches:  r.WebFetches,
+		}
+	}
+	for i := range sessions {
+		s := &sessions[i]
+		b.Sessions[i] = Session{
+			ID:              s.ID,
+			Date:            s.Date,
+			Project:         s.Project,
+			CWD:             s.CWD,
+			Repo:            s.Repo,
+			Branch:          s.Branch,
+			DurationSeconds: int64(s.Duration / time.Second),
+			ActiveSeconds:   int64(s.Active / time.Second),
+		}
+	}
+	return b
+}
+
+// Write encodes b as JSON to w.
+func Write(w io.Writer, b *Bundle) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return fmt.Errorf("encoding bundle: %w", err)
+	}
+	return nil
+}
+
+// Read decodes a bundle from r, rejecting other JSON documents and bundles
+// written by a newer, incompatible version.
+func Read(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("decoding bundle: %w", err)
+	}
+	if b.Format != Format {
+		return nil, errors.New("not a ccost bundle")
+	}
+	if b.Version < 1 || b.Version > Version {
+		return nil, fmt.Errorf("unsupported bundle version %d (want 1 to %d)", b.Version, Version)
+	}
+	return &b, nil
+}
+
+// ReadFile reads the bundle at path.
+func ReadFile(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	b, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// InZone reports whether b was exported with days in loc, judged by the
+// offsets its record times were written with. A bundle without records
+// can't tell and passes.
+func (b *Bundle) InZone(loc *time.Location) bool {
+	for i := range b.Records {
+		t := b.Records[i].Time
+		_, offset := t.Zone()
+		if _, want := t.In(loc).Zone(); offset != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge combines bundles into records and sessions labelled with each
+// bundle's user. A record exported more than once (overlapping snapshots,
+// or the same logs synced to two machines) is kept from the first bundle
+// that contains it, matched by message ID. Session days whose records were
+// all kept from an earlier bundle are dropped with them, even when their
+// session IDs differ, as between a redacted and a plain export. Otherwise
+// the time a session spent on a branch in a day is kept once, the longest
+// seen; sessions without an ID can't be matched and are all kept.
+func Merge(bundles []*Bundle) ([]parser.Record, []parser.Session) {
+	var records []parser.Record
+	var sessions []parser.Session
+	seen := map[string]bool{}
+	days := map[[3]string]int{} // session ID, date and branch → index in sessions
+	for _, b := range bundles {
+		kept := map[[2]string]bool{}    // session ID and date with a new record
+		dupOnly := map[[2]string]bool{} // session ID and date with a duplicate record
+		for i := range b.Records {
+			r := &b.Records[i]
+			day := [2]string{r.Session, r.Time.Format("2006-01-02")}
+			if r.ID != "" {
+				if seen[r.ID] {
+					dupOnly[day] = true
+					continue
+				}
+				seen[r.ID] = true
+			}
+			kept[day] = true
+			records = append(records, parser.Record{
+				ID:          r.ID,
+				Time:        r.Time,
+				Model:       r.Model,
+				Project:     r.Project,
+				CWD:         r.CWD,
+				Repo:        r.Repo,
+				Branch:      r.Branch,
+				Session:     r.Session,
+				IsSubagent:  r.AgentID != "",
+				AgentID:     r.AgentID,
+				Tools:       r.Tools,
+				Input:       r.Input,
+				Output:      r.Output,
+				CacheWrite:  r.CacheWrite,
+				CacheRead:   r.CacheRead,
+				WebSearches: r.WebSearches,
+				WebFetches:  r.WebFetches,
+				User:        b.User,
+			})
+		}
+		for i := range b.Sessions {
+			s := &b.Sessions[i]
+			if day := [2]string{s.ID, s.Date}; dupOnly[day] && !kept[day] {
+				continue
+			}
+			ps := parser.Session{
+				ID:       s.ID,
+				Date:     s.Date,
+				Project:  s.Project,
+				CWD:      s.CWD,
+				Repo:     s.Repo,
+				Branch:   s.Branch,
+				Duration: time.Duration(s.DurationSeconds) * time.Second,
+				Active:   time.Duration(s.ActiveSeconds) * time.Second,
+				User:     b.User,
+			}
+			if s.ID == "" {
+				sessions = append(sessions, ps)
+				continue
+			}
+			key := [3]string{s.ID, s.Date, s.Branch}
+			if j, ok := days[key]; ok {
+				if ps.Duration > sessions[j].Duration {
+					ps.User = sessions[j].User
+					sessions[j] = ps
+				}
+				continue
+			}
+			days[key] = len(sessions)
+			sessions = append(sessions, ps)
+		}
+	}
+	return records, sessions
+}
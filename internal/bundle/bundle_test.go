@@ -0,0 +1,122 @@
+package bundle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+func TestRoundTrip(t *testing.T) {
+	records := []parser.Record{
+		{ID: "m1", Time: time.Date(2026, 2, 1, 10, 0, 0, 0, time.FixedZone("CET", 3600)), Model: "claude-opus-4-6", Project: "shop", CWD: "/src/shop", Session: "s1", Input: 100, Output: 10},
+		{ID: "m2", Time: time.Date(2026, 2, 1, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Session: "s1", IsSubagent: true, AgentID: "agent-x", Tools: []string{"Bash"}, WebSearches: 2},
+	}
+	sessions := []parser.Session{{ID: "s1", Date: "2026-02-01", Project: "shop", Duration: time.Hour, Active: 40 * time.Minute}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, New(records, sessions, "alice", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotRecords, gotSessions := Merge([]*Bundle{b})
+	if len(gotRecords) != 2 || len(gotSessions) != 1 {
+		t.Fatalf("expected 2 records and 1 session, got %d and %d", len(gotRecords), len(gotSessions))
+	}
+	if r := gotRecords[0]; !r.Time.Equal(records[0].Time) || r.User != "alice" || r.CWD != "/src/shop" || r.Input != 100 {
+		t.Errorf("unexpected first record %+v", r)
+	}
+	if r := gotRecords[1]; !r.IsSubagent || r.AgentID != "agent-x" || len(r.Tools) != 1 || r.WebSearches != 2 {
+		t.Errorf("unexpected subagent record %+v", r)
+	}
+	if s := gotSessions[0]; s.Active != 40*time.Minute || s.Duration != time.Hour || s.User != "alice" {
+		t.Errorf("unexpected session %+v", s)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	for _, doc := range []string{`{"rows": []}`, `{"format": "ccost-bundle", "version": 2}`, `not json`} {
+		if _, err := Read(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected an error for %s", doc)
+		}
+	}
+}
+
+func TestMergeDedup(t *testing.T) {
+	at := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	a := &Bundle{User: "alice",
+		Records:  []Record{{ID: "m1", Time: at, Input: 100}, {ID: "m2", Time: at, Input: 200}},
+		Sessions: []Session{{ID: "s1", Date: "2026-02-01", DurationSeconds: 600}},
+	}
+	b := &Bundle{User: "bob",
+		Records:  []Record{{ID: "m2", Time: at, Input: 200}, {ID: "m3", Time: at, Input: 300}, {Time: at}, {Time: at}},
+		Sessions: []Session{{ID: "s1", Date: "2026-02-01", DurationSeconds: 900}, {ID: "s2", Date: "2026-02-01"}},
+	}
+
+	records, sessions := Merge([]*Bundle{a, b})
+	ids := make([]string, 0, len(records))
+	for i := range records {
+		ids = append(ids, records[i].ID+"/"+records[i].User)
+	}
+	// Records without an ID can't be matched and are all kept.
+	if got := strings.Join(ids, ","); got != "m1/alice,m2/alice,m3/bob,/bob,/bob" {
+		t.Errorf("unexpected merged records %s", got)
+	}
+	if len(sessions) != 2 || sessions[0].Duration != 15*time.Minute || sessions[0].User != "alice" {
+		t.Errorf("expected the longer copy of s1 kept for alice, got %+v", sessions)
+	}
+}
+
+func TestMergeDropsDuplicatedSessions(t *testing.T) {
+	at := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	plain := &Bundle{User: "alice",
+		Records:  []Record{{ID: "m1", Session: "s1", Time: at}, {ID: "m2", Session: "s1", Time: at}},
+		Sessions: []Session{{ID: "s1", Date: "2026-02-01", DurationSeconds: 600}},
+	}
+	// The same logs exported again with --redact: session IDs are dropped,
+	// so only the records show that the session time was already counted.
+	redacted := &Bundle{User: "alice",
+		Records: []Record{{ID: "m1", Time: at}, {ID: "m2", Time: at}, {ID: "m3", Time: at.AddDate(0, 0, 1)}},
+		Sessions: []Session{
+			{Date: "2026-02-01", DurationSeconds: 600},
+			{Date: "2026-02-02", DurationSeconds: 300},
+			{Date: "2026-02-03", DurationSeconds: 60},
+		},
+	}
+
+	_, sessions := Merge([]*Bundle{plain, redacted})
+	dates := make([]string, 0, len(sessions))
+	for i := range sessions {
+		dates = append(dates, sessions[i].ID+"/"+sessions[i].Date)
+	}
+	// Session days without records are kept: there is nothing to match them by.
+	if got := strings.Join(dates, ","); got != "s1/2026-02-01,/2026-02-02,/2026-02-03" {
+		t.Errorf("unexpected merged sessions %s", got)
+	}
+}
+
+func TestInZone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	// Offsets change with daylight saving time; both are Berlin's.
+	b := &Bundle{Records: []Record{
+		{Time: time.Date(2026, 1, 10, 9, 0, 0, 0, time.FixedZone("", 3600))},
+		{Time: time.Date(2026, 7, 10, 9, 0, 0, 0, time.FixedZone("", 7200))},
+	}}
+	if !b.InZone(berlin) {
+		t.Error("expected a bundle exported in Berlin to be in Berlin's zone")
+	}
+	if b.InZone(time.UTC) {
+		t.Error("expected a bundle exported in Berlin not to be in UTC")
+	}
+	if !(&Bundle{}).InZone(time.UTC) {
+		t.Error("expected a bundle without records to pass")
+	}
+}
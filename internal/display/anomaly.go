@@ -0,0 +1,110 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// anomalyWhat identifies the flagged item within its day.
+func anomalyWhat(a *report.Anomaly) string {
+	switch a.Kind {
+	case report.AnomalySession:
+		return shortID(a.Key)
+	case report.AnomalyRequest:
+		if t, err := time.Parse(time.RFC3339, a.Key); err == nil {
+			return t.Format("15:04:05")
+		}
+		return a.Key
+	default:
+		return ""
+	}
+}
+
+// AnomaliesTable writes flagged days, sessions and requests to w with their
+// baseline and context.
+func AnomaliesTable(w io.Writer, anomalies []report.Anomaly, title string) {
+	tw := table.NewWriter()
+	tw.SetOutputMirror(w)
+	if title != "" {
+		tw.SetTitle(text.FgCyan.Sprint(title))
+	}
+	tw.AppendHeader(table.Row{"Date", "Kind", "Project", "What", "Cost", "Median", "×", "Context"})
+
+	for i := range anomalies {
+		a := &anomalies[i]
+		tw.AppendRow(table.Row{
+			a.Date,
+			string(a.Kind),
+			a.Project,
+			anomalyWhat(a),
+			text.FgRed.Sprint(formatCost(a.Cost)),
+			formatCost(a.Median),
+			fmt.Sprintf("%.1f×", a.Multiple()),
+			a.Detail,
+		})
+	}
+
+	var colConfigs []table.ColumnConfig
+	for i := 5; i <= 7; i++ {
+		colConfigs = append(colConfigs, table.ColumnConfig{
+			Number:      i,
+			Align:       text.AlignRight,
+			AlignHeader: text.AlignRight,
+		})
+	}
+	tw.SetColumnConfigs(colConfigs)
+
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Color.Header = text.Colors{text.FgCyan}
+	tw.Style().Options.DoNotColorBordersAndSeparators = true
+
+	tw.Render()
+}
+
+type jsonAnomaly struct {
+	Kind      string  `json:"kind"`
+	Date      string  `json:"date"`
+	Project   string  `json:"project"`
+	Key       string  `json:"key"`
+	Context   string  `json:"context"`
+	Cost      float64 `json:"cost"`
+	Median    float64 `json:"baseline_median"`
+	MAD       float64 `json:"baseline_mad"`
+	Threshold float64 `json:"threshold"`
+	Multiple  float64 `json:"multiple"`
+	Requests  int     `json:"requests"`
+}
+
+// AnomaliesJSON writes anomalies as a JSON array to w.
+func AnomaliesJSON(w io.Writer, anomalies []report.Anomaly) error {
+	out := make([]jsonAnomaly, len(anomalies))
+	for i := range anomalies {
+		a := &anomalies[i]
+		out[i] = jsonAnomaly{
+			Kind:      string(a.Kind),
+			Date:      a.Date,
+			Project:   a.Project,
+			Key:       a.Key,
+			Context:   a.Detail,
+			Cost:      roundCost(a.Cost),
+			Median:    roundCost(a.Median),
+			MAD:       roundCost(a.MAD),
+			Threshold: roundCost(a.Threshold),
+			Multiple:  math.Round(a.Multiple()*100) / 100,
+			Requests:  a.Requests,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("encoding anomalies: %w", err)
+	}
+	return nil
+}
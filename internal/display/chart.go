@@ -0,0 +1,167 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// Block-character ramps. Bars use eighth blocks for sub-cell precision; the
+// ASCII fallback only has whole cells.
+var (
+	barEighths   = []rune(" ▏▎▍▌▋▊▉█")
+	sparkUnicode = []rune("▁▂▃▄▅▆▇█")
+	sparkASCII   = []rune("_.-~=+*#")
+)
+
+// maxLabelWidth caps the label column so long project names don't squeeze
+// the bars.
+const maxLabelWidth = 24
+
+// Bars writes a horizontal bar chart of cost per row key to w, scaled to fit
+// width columns. Rows sharing a key (per-model detail) are summed. Keys with
+// unknown cost are drawn without a bar.
+func Bars(w io.Writer, rpt *report.Report, width int, ascii bool) {
+	var keys []string
+	costs := map[string]float64{}
+	for _, r := range rpt.Rows { //nolint:gocritic // rangeValCopy: only run once per report
+		c, seen := costs[r.Key]
+		if !seen {
+			keys = append(keys, r.Key)
+		}
+		if r.Cost < 0 || c < 0 {
+			costs[r.Key] = -1
+		} else {
+			costs[r.Key] = c + r.Cost
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	years := map[string]bool{}
+	for _, k := range keys {
+		years[yearOf(k)] = true
+	}
+	labels := make([]string, len(keys))
+	labelWidth, valueWidth := 0, 0
+	maxCost := 0.0
+	for i, k := range keys {
+		label := k
+		if len(years) == 1 {
+			label = trimDate(k, false)
+		}
+		labels[i] = text.Trim(label, maxLabelWidth)
+		labelWidth = max(labelWidth, text.RuneWidthWithoutEscSequences(labels[i]))
+		valueWidth = max(valueWidth, len(formatCost(costs[k])))
+		maxCost = max(maxCost, costs[k])
+	}
+	barWidth := max(width-labelWidth-valueWidth-2, 1)
+
+	for i, k := range keys {
+		bar := ""
+		if c := costs[k]; c > 0 && maxCost > 0 {
+			bar = renderBar(c/maxCost*float64(barWidth), ascii)
+		}
+		pad := barWidth - text.RuneWidthWithoutEscSequences(bar)
+		_, _ = fmt.Fprintf(w, "%s %s%s %*s\n",
+			text.Pad(labels[i], labelWidth, ' '),
+			text.FgCyan.Sprint(bar), strings.Repeat(" ", pad),
+			valueWidth, formatCost(costs[k]))
+	}
+}
+
+// renderBar draws a bar cells wide, using eighth blocks for the fractional
+// remainder (or whole '#' cells in ASCII mode).
+func renderBar(cells float64, ascii bool) string {
+	if ascii {
+		return strings.Repeat("#", max(int(cells+0.5), 1))
+	}
+	full := int(cells)
+	eighths := int((cells - float64(full)) * 8)
+	bar := strings.Repeat(string(barEighths[8]), full)
+	if eighths > 0 {
+		bar += string(barEighths[eighths])
+	}
+	if bar == "" {
+		bar = string(barEighths[1])
+	}
+	return bar
+}
+
+// Sparkline writes a one-line daily cost trend to w from a report grouped by
+// date. Missing days count as zero; when there are more days than fit in
+// width, consecutive days are summed into buckets.
+func Sparkline(w io.Writer, daily *report.Report, width int, ascii bool) {
+	if len(daily.Rows) == 0 {
+		return
+	}
+	byDate := map[string]float64{}
+	for _, r := range daily.Rows { //nolint:gocritic // rangeValCopy: only run once per report
+		if r.Cost > 0 {
+			byDate[r.Key] += r.Cost
+		}
+	}
+	first, err1 := time.Parse("2006-01-02", daily.Rows[0].Key)
+	last, err2 := time.Parse("2006-01-02", daily.Rows[len(daily.Rows)-1].Key)
+	if err1 != nil || err2 != nil {
+		return
+	}
+
+	var values []float64
+	total := 0.0
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		v := byDate[d.Format("2006-01-02")]
+		values = append(values, v)
+		total += v
+	}
+
+	label := fmt.Sprintf("Daily cost %s – %s ", first.Format("Jan 02"), last.Format("Jan 02"))
+	// No bucket can exceed the total, so its label is the widest the peak
+	// label can get.
+	peakWidth := len("  peak " + formatCost(total))
+	values = bucket(values, max(width-text.RuneWidthWithoutEscSequences(label)-peakWidth, 1))
+
+	peak := 0.0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	_, _ = fmt.Fprintf(w, "%s%s  peak %s\n", label, text.FgCyan.Sprint(sparkline(values, peak, ascii)), formatCost(peak))
+}
+
+// bucket sums consecutive values so that at most n remain.
+func bucket(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+	size := (len(values) + n - 1) / n
+	out := make([]float64, 0, n)
+	for i := 0; i < len(values); i += size {
+		sum := 0.0
+		for _, v := range values[i:min(i+size, len(values))] {
+			sum += v
+		}
+		out = append(out, sum)
+	}
+	return out
+}
+
+func sparkline(values []float64, peak float64, ascii bool) string {
+	ramp := sparkUnicode
+	if ascii {
+		ramp = sparkASCII
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if peak > 0 {
+			i = min(int(v/peak*float64(len(ramp)-1)+0.5), len(ramp)-1)
+		}
+		b.WriteRune(ramp[i])
+	}
+	return b.String()
+}
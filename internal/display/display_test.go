@@ -3,11 +3,14 @@ package display
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
+	"github.com/zulerne/ccost/internal/parser"
 	"github.com/zulerne/ccost/internal/report"
 )
 
@@ -45,7 +48,7 @@ func sampleReport() report.Report {
 func TestTable(t *testing.T) {
 	var buf bytes.Buffer
 	rpt := sampleReport()
-	Table(&buf, &rpt, "Date", false, "")
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date"})
 	out := strings.ToUpper(stripANSI(buf.String()))
 
 	if !strings.Contains(out, "DATE") {
@@ -185,7 +188,7 @@ func TestFormatCompact(t *testing.T) {
 func TestTableExact(t *testing.T) {
 	var buf bytes.Buffer
 	rpt := sampleReport()
-	Table(&buf, &rpt, "Date", true, "")
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date", Exact: true})
 	out := strings.ToUpper(stripANSI(buf.String()))
 
 	if !strings.Contains(out, "19,290") {
@@ -202,7 +205,7 @@ func TestTableWithModels(t *testing.T) {
 		Total: report.Row{Key: "TOTAL", Input: 3000, Output: 1500, Cost: 0.07},
 	}
 	var buf bytes.Buffer
-	Table(&buf, &rpt, "Date", false, "")
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date"})
 	out := stripANSI(buf.String())
 
 	if !strings.Contains(strings.ToUpper(out), "MODEL") {
@@ -224,8 +227,629 @@ func TestTableByProject(t *testing.T) {
 		Total: report.Row{Key: "TOTAL", Input: 100, Output: 50, Cost: 0.005},
 	}
 	var buf bytes.Buffer
-	Table(&buf, &rpt, "Project", false, "")
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Project"})
 	if !strings.Contains(strings.ToUpper(stripANSI(buf.String())), "PROJECT") {
 		t.Error("expected 'PROJECT' header")
 	}
 }
+
+func TestTableBranchKeys(t *testing.T) {
+	// Branch names with a dash after four characters are not dates.
+	rpt := report.Report{
+		Rows: []report.Row{
+			{Key: "feat-login", Cost: 2},
+			{Key: "docs-readme", Cost: 1},
+		},
+		Total: report.Row{Key: "TOTAL", Cost: 3},
+	}
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Branch"})
+	out := stripANSI(buf.String())
+	if !strings.Contains(out, "feat-login") || !strings.Contains(out, "docs-readme") {
+		t.Errorf("expected full branch names:\n%s", out)
+	}
+	for line := range strings.SplitSeq(out, "\n") {
+		if f := strings.Fields(strings.Trim(line, "│ ")); len(f) == 1 && (f[0] == "feat" || f[0] == "docs") {
+			t.Errorf("expected no year header rows, got %q:\n%s", line, out)
+		}
+	}
+}
+
+func TestBars(t *testing.T) {
+	rpt := report.Report{
+		Rows: []report.Row{
+			{Key: "alpha", Model: "claude-opus-4-6", Cost: 6},
+			{Key: "alpha", Model: "claude-haiku-4-5", Cost: 4},
+			{Key: "beta", Cost: 5},
+			{Key: "gamma", Cost: -1},
+		},
+	}
+	var buf bytes.Buffer
+	Bars(&buf, &rpt, 30, true)
+	lines := strings.Split(strings.TrimRight(stripANSI(buf.String()), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 bars (one per key), got %d:\n%s", len(lines), buf.String())
+	}
+	// 30 columns - 5 label - 6 value - 2 spaces = 17 bar cells.
+	if !strings.Contains(lines[0], strings.Repeat("#", 17)+" $10.00") {
+		t.Errorf("expected full-width bar for the largest key, got %q", lines[0])
+	}
+	if n := strings.Count(lines[1], "#"); n != 9 {
+		t.Errorf("expected half-width bar (9 cells) for beta, got %d in %q", n, lines[1])
+	}
+	if strings.Contains(lines[2], "#") || !strings.HasSuffix(lines[2], "N/A") {
+		t.Errorf("expected no bar and N/A for unknown cost, got %q", lines[2])
+	}
+	for _, l := range lines {
+		if w := len(l); w != 30 {
+			t.Errorf("expected line width 30, got %d: %q", w, l)
+		}
+	}
+}
+
+func TestRenderBarUnicode(t *testing.T) {
+	if got := renderBar(2.5, false); got != "██▌" {
+		t.Errorf("renderBar(2.5) = %q, want %q", got, "██▌")
+	}
+	if got := renderBar(0.01, false); got != "▏" {
+		t.Errorf("renderBar(0.01) = %q, want a minimal sliver", got)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	daily := report.Report{
+		Rows: []report.Row{
+			{Key: "2026-02-10", Cost: 1},
+			{Key: "2026-02-12", Cost: 8},
+			{Key: "2026-02-13", Cost: 4},
+		},
+	}
+	var buf bytes.Buffer
+	Sparkline(&buf, &daily, 80, false)
+	out := stripANSI(buf.String())
+	// The missing 2026-02-11 shows as the lowest level.
+	if !strings.Contains(out, "▂▁█▅") {
+		t.Errorf("expected sparkline ▂▁█▅ in output: %q", out)
+	}
+	if !strings.Contains(out, "Feb 10 – Feb 13") || !strings.Contains(out, "peak $8.00") {
+		t.Errorf("expected range and peak in output: %q", out)
+	}
+
+	buf.Reset()
+	Sparkline(&buf, &daily, 80, true)
+	if strings.ContainsAny(stripANSI(buf.String()), "▁▂▃▄▅▆▇█") {
+		t.Errorf("expected ASCII sparkline, got %q", buf.String())
+	}
+
+	// Costly months still fit: the peak label is measured, not assumed.
+	var month report.Report
+	for d := 1; d <= 28; d++ {
+		month.Rows = append(month.Rows, report.Row{Key: fmt.Sprintf("2026-02-%02d", d), Cost: 123456.78})
+	}
+	buf.Reset()
+	Sparkline(&buf, &month, 50, false)
+	line := strings.TrimSuffix(stripANSI(buf.String()), "\n")
+	if w := utf8.RuneCountInString(line); w > 50 {
+		t.Errorf("sparkline is %d wide, want at most 50: %q", w, line)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	got := bucket([]float64{1, 2, 3, 4, 5}, 2)
+	if len(got) != 2 || got[0] != 6 || got[1] != 9 {
+		t.Errorf("bucket = %v, want [6 9]", got)
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	h := report.Heatmap{Metric: report.MetricCost}
+	h.Cells[0][9] = 4
+	h.Cells[4][14] = 1
+
+	var buf bytes.Buffer
+	HeatmapGrid(&buf, &h, "Heatmap", false, true)
+	lines := strings.Split(stripANSI(buf.String()), "\n")
+	if !strings.HasPrefix(lines[2], "Mon  "+strings.Repeat("..", 9)+"##") || !strings.HasSuffix(lines[2], "$4.00") {
+		t.Errorf("expected peak cell at Mon 09 with total $4.00, got %q", lines[2])
+	}
+	if !strings.Contains(lines[6], "--") {
+		t.Errorf("expected low shade on Fri, got %q", lines[6])
+	}
+
+	buf.Reset()
+	if err := HeatmapJSON(&buf, &h); err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Metric   string      `json:"metric"`
+		Weekdays []string    `json:"weekdays"`
+		Values   [][]float64 `json:"values"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if out.Metric != "cost" || len(out.Weekdays) != 7 || out.Weekdays[0] != "Monday" {
+		t.Errorf("unexpected header fields: %+v", out)
+	}
+	if len(out.Values) != 7 || len(out.Values[0]) != 24 || out.Values[0][9] != 4 {
+		t.Errorf("expected 7x24 matrix with Mon 09 = 4, got %v", out.Values)
+	}
+}
+
+func TestTableCache(t *testing.T) {
+	rpt := sampleReport()
+	rpt.Rows[0].CacheSaved = 25.5
+	rpt.Rows[0].CacheWritePremium = 30
+	rpt.Total = rpt.Rows[0]
+
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date"})
+	if strings.Contains(strings.ToUpper(stripANSI(buf.String())), "ROI") {
+		t.Error("expected no cache columns without Cache option")
+	}
+
+	buf.Reset()
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date", Cache: true})
+	out := stripANSI(buf.String())
+	for _, want := range []string{"HIT", "SAVED", "ROI", "98%", "$25.50", "-$4.50"} {
+		if !strings.Contains(strings.ToUpper(out), want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := JSON(&buf, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Total struct {
+			HitRatio float64 `json:"cache_hit_ratio"`
+			Saved    float64 `json:"cache_saved"`
+			ROI      float64 `json:"cache_roi"`
+		} `json:"total"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.Total.ROI != -4.5 || result.Total.Saved != 25.5 {
+		t.Errorf("expected cache_roi -4.5 and cache_saved 25.5 in JSON, got %+v", result.Total)
+	}
+	if r := result.Total.HitRatio; r < 0.97 || r > 0.99 {
+		t.Errorf("expected cache_hit_ratio ~0.98, got %v", r)
+	}
+}
+
+func TestPlanTable(t *testing.T) {
+	rpt := report.Report{
+		Rows:  []report.Row{{Key: "2026-02-10", Cost: 30}},
+		Total: report.Row{Key: "TOTAL", Cost: 30},
+		Plan: []report.PlanMonth{
+			{Month: "2026-02", Users: 2, Cost: 30, Fee: 10, BreakEven: "2026-02-10"},
+			{Month: "2026-03", Users: 2, Cost: 5, Fee: 10},
+		},
+	}
+
+	var buf bytes.Buffer
+	PlanTable(&buf, rpt.Plan, "Plan · pro")
+	out := stripANSI(buf.String())
+	for _, want := range []string{"Plan · pro", "2026-02", "$15.00", "3.0×", "Feb 10", "0.5×", "—"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := JSON(&buf, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Plan []struct {
+			Month     string  `json:"month"`
+			PerUser   float64 `json:"api_cost_per_user"`
+			Multiple  float64 `json:"value_multiple"`
+			BreakEven string  `json:"break_even"`
+		} `json:"plan"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(result.Plan) != 2 {
+		t.Fatalf("expected 2 plan months, got %d", len(result.Plan))
+	}
+	if p := result.Plan[0]; p.PerUser != 15 || p.Multiple != 3 || p.BreakEven != "2026-02-10" {
+		t.Errorf("unexpected first plan month: %+v", p)
+	}
+	if result.Plan[1].BreakEven != "" {
+		t.Errorf("expected no break-even for March, got %q", result.Plan[1].BreakEven)
+	}
+}
+
+func TestPlanTablePartialMonth(t *testing.T) {
+	months := []report.PlanMonth{
+		{Month: "2026-03", Users: 1, Cost: 20, Fee: 20, Days: 31, MonthDays: 31},
+		{Month: "2026-04", Users: 1, Cost: 5, Fee: 4, Days: 6, MonthDays: 30},
+	}
+	var buf bytes.Buffer
+	PlanTable(&buf, months, "Plan · pro")
+	out := stripANSI(buf.String())
+	if !strings.Contains(out, "2026-04 (6/30 days)") || !strings.Contains(out, "1.2×") {
+		t.Errorf("expected April flagged as partial with a prorated value:\n%s", out)
+	}
+	if strings.Contains(out, "2026-03 (") {
+		t.Errorf("expected March not flagged:\n%s", out)
+	}
+}
+
+func TestTableSplitAgents(t *testing.T) {
+	rpt := sampleReport()
+	rpt.Rows[0].AgentCost = rpt.Rows[0].Cost / 4
+	rpt.Rows[0].Agents = 3
+	rpt.Total = rpt.Rows[0]
+
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date", Agents: true})
+	out := strings.ToUpper(stripANSI(buf.String()))
+	for _, want := range []string{"MAIN", "AGENTS", "AGENT %", "SPAWNED", "25%"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestTableServerColumn(t *testing.T) {
+	rpt := sampleReport()
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date"})
+	if strings.Contains(strings.ToUpper(stripANSI(buf.String())), "SERVER") {
+		t.Error("expected no Server column without server tool fees")
+	}
+
+	rpt.Rows[0].ServerToolCost = 0.42
+	rpt.Total.ServerToolCost = 0.42
+	buf.Reset()
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date"})
+	out := stripANSI(buf.String())
+	if !strings.Contains(strings.ToUpper(out), "SERVER") || !strings.Contains(out, "$0.42") {
+		t.Errorf("expected Server column with $0.42:\n%s", out)
+	}
+}
+
+func TestTableActiveColumn(t *testing.T) {
+	rpt := sampleReport()
+	rpt.Rows[0].Active = 90 * time.Minute
+	rpt.Total.Active = 90 * time.Minute
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date"})
+	if strings.Contains(strings.ToUpper(stripANSI(buf.String())), "ACTIVE") {
+		t.Error("expected no Active column by default")
+	}
+
+	buf.Reset()
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date", Active: true})
+	out := stripANSI(buf.String())
+	if !strings.Contains(strings.ToUpper(out), "ACTIVE") || !strings.Contains(out, formatDuration(90*time.Minute)) {
+		t.Errorf("expected Active column with 1h30m:\n%s", out)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 14, 10, 30, 0, 0, time.UTC), Project: "shop", Session: "0d9b2c4e-aaaa", Model: "claude-opus-4-6", Input: 1_000_000, Tools: []string{"Bash"}},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Project: "blog", Session: "s2", Model: "unknown-model", Output: 10},
+	}
+
+	var buf bytes.Buffer
+	RecordsTable(&buf, records, "Records", false)
+	out := stripANSI(buf.String())
+	for _, want := range []string{"2026-02-14 10:30", "0d9b2c4e", "opus-4-6", "$5.00", "N/A", "TOTAL (2)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := RecordsCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "time,project,") {
+		t.Fatalf("expected header and 2 rows, got:\n%s", buf.String())
+	}
+	if !strings.HasSuffix(lines[1], ",5.000000") || !strings.HasSuffix(lines[2], ",") {
+		t.Errorf("expected cost 5 and an empty unknown cost, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := RecordsJSON(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	var result []struct {
+		Time    string   `json:"time"`
+		Session string   `json:"session"`
+		Tools   []string `json:"tools"`
+		Cost    float64  `json:"cost"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(result) != 2 || result[0].Time != "2026-02-14T10:30:00Z" || result[0].Cost != 5 || result[1].Cost != -1 {
+		t.Errorf("unexpected JSON records: %+v", result)
+	}
+	if result[0].Session != "0d9b2c4e-aaaa" || len(result[0].Tools) != 1 {
+		t.Errorf("expected full session ID and tools in JSON, got %+v", result[0])
+	}
+}
+
+func TestRecordsJSONFields(t *testing.T) {
+	records := []parser.Record{{
+		ID: "msg_001", Time: time.Date(2026, 2, 14, 10, 30, 0, 0, time.UTC), Project: "shop", CWD: "/src/shop",
+		Session: "s1", User: "ana", Model: "claude-opus-4-6", WebFetches: 2,
+	}}
+	var buf bytes.Buffer
+	if err := RecordsJSON(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	var result []struct {
+		ID         string `json:"id"`
+		CWD        string `json:"cwd"`
+		User       string `json:"user"`
+		WebFetches int    `json:"web_fetch_requests"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(result) != 1 || result[0].ID != "msg_001" || result[0].CWD != "/src/shop" || result[0].User != "ana" || result[0].WebFetches != 2 {
+		t.Errorf("expected id, cwd, user and web fetches in JSON, got %+v", result)
+	}
+
+	// Empty values are omitted rather than written as "".
+	records[0].ID, records[0].CWD, records[0].User, records[0].WebFetches = "", "", "", 0
+	buf.Reset()
+	if err := RecordsJSON(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"id"`, `"cwd"`, `"user"`, `"web_fetch_requests"`} {
+		if strings.Contains(buf.String(), key) {
+			t.Errorf("expected %s to be omitted when empty:\n%s", key, buf.String())
+		}
+	}
+}
+
+func TestTableNested(t *testing.T) {
+	rpt := report.Report{
+		Dims: []report.Dimension{report.Project, report.Model},
+		Rows: []report.Row{
+			{Key: "shop", Input: 300, Cost: 3, Children: []report.Row{
+				{Key: "claude-haiku-4-5", Input: 100, Cost: 1},
+				{Key: "claude-opus-4-6", Input: 200, Cost: 2},
+			}},
+		},
+		Total: report.Row{Key: "TOTAL", Input: 300, Cost: 3},
+	}
+
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Project › Model"})
+	out := stripANSI(buf.String())
+	for _, want := range []string{"│ shop ", "│   haiku-4-5 ", "│   opus-4-6 ", "$3.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := JSON(&buf, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Dims []string `json:"dimensions"`
+		Rows []struct {
+			Key      string `json:"key"`
+			Children []struct {
+				Key   string `json:"key"`
+				Input int    `json:"input_tokens"`
+			} `json:"children"`
+		} `json:"rows"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(result.Dims) != 2 || len(result.Rows) != 1 || len(result.Rows[0].Children) != 2 {
+		t.Fatalf("unexpected nested JSON: %s", buf.String())
+	}
+	if c := result.Rows[0].Children[1]; c.Key != "claude-opus-4-6" || c.Input != 200 {
+		t.Errorf("unexpected child: %+v", c)
+	}
+}
+
+func TestPivotOutput(t *testing.T) {
+	p := report.Pivot{
+		Rows:      report.Date,
+		Cols:      report.Model,
+		Metric:    report.MetricCost,
+		RowKeys:   []string{"2026-02-14", "2026-02-15"},
+		ColKeys:   []string{"claude-haiku-4-5", "claude-opus-4-6"},
+		Values:    [][]float64{{1, 5}, {0, 10.004}},
+		RowTotals: []float64{6, 10.004},
+		ColTotals: []float64{1, 15.004},
+		Total:     16.004,
+	}
+
+	var buf bytes.Buffer
+	PivotTable(&buf, &p, "Pivot", false)
+	out := stripANSI(buf.String())
+	for _, want := range []string{`DATE \ MODEL`, "HAIKU-4-5", "02-15", "$10.00", "$16.00"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := PivotCSV(&buf, &p); err != nil {
+		t.Fatal(err)
+	}
+	want := `date\model,claude-haiku-4-5,claude-opus-4-6,total
+2026-02-14,1,5,6
+2026-02-15,0,10,10
+total,1,15,16
+`
+	if buf.String() != want {
+		t.Errorf("unexpected CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := PivotJSON(&buf, &p); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Rows   string      `json:"rows"`
+		Values [][]float64 `json:"values"`
+		Total  float64     `json:"total"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if result.Rows != "date" || result.Values[1][1] != 10 || result.Total != 16 {
+		t.Errorf("unexpected JSON: %s", buf.String())
+	}
+}
+
+func TestCumulativeOutput(t *testing.T) {
+	rpt := sampleReport()
+	rpt.Rows[0].CumCost, rpt.Rows[0].CumTokens = 12.8, 6585703
+	rpt.Cumulative = []report.CumulativePoint{{Date: "2026-02-14", Cost: 12.8, Tokens: 6585703}}
+
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date", Cumulative: true})
+	out := strings.ToUpper(stripANSI(buf.String()))
+	for _, want := range []string{"CUM. TOKENS", "CUM. COST", "6.6M"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := JSON(&buf, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Rows []struct {
+			CumCost *float64 `json:"cumulative_cost"`
+		} `json:"rows"`
+		Cumulative []struct {
+			Date   string  `json:"date"`
+			Cost   float64 `json:"cost"`
+			Tokens int     `json:"tokens"`
+		} `json:"cumulative"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(result.Cumulative) != 1 || result.Cumulative[0].Tokens != 6585703 {
+		t.Errorf("unexpected cumulative series: %+v", result.Cumulative)
+	}
+	if c := result.Rows[0].CumCost; c == nil || *c != 12.8 {
+		t.Errorf("expected cumulative_cost 12.8 on the row, got %v", c)
+	}
+}
+
+func TestAnomaliesOutput(t *testing.T) {
+	anomalies := []report.Anomaly{
+		{Kind: report.AnomalyDay, Date: "2026-02-06", Project: "shop", Key: "2026-02-06", Detail: "2 sessions, 12 requests", Cost: 21, Median: 2, MAD: 0.1, Requests: 12},
+		{Kind: report.AnomalyRequest, Date: "2026-02-06", Project: "shop", Key: "2026-02-06T11:00:00Z", Detail: "haiku-4-5 · Bash", Cost: 20, Median: 1, MAD: 0.05, Requests: 1},
+	}
+
+	var buf bytes.Buffer
+	AnomaliesTable(&buf, anomalies, "Anomalies")
+	out := stripANSI(buf.String())
+	for _, want := range []string{"Anomalies", "request", "11:00:00", "$21.00", "10.5×", "haiku-4-5 · Bash"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in table:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := AnomaliesJSON(&buf, anomalies); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1]["kind"] != "request" || got[0]["multiple"] != 10.5 || got[1]["baseline_median"] != 1.0 {
+		t.Errorf("unexpected JSON: %s", buf.String())
+	}
+}
+
+func TestRatesOutput(t *testing.T) {
+	rpt := sampleReport()
+	rpt.Rows[0].Active = 2 * time.Hour
+	rpt.Total.Active = 2 * time.Hour
+	rpt.ComputeRates()
+
+	var buf bytes.Buffer
+	Table(&buf, &rpt, TableOptions{KeyHeader: "Date", Rates: true})
+	out := strings.ToUpper(stripANSI(buf.String()))
+	for _, want := range []string{"$/H", "TOK/MIN", "OUT/IN"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	// JSON always carries the rates, with or without --rates.
+	buf.Reset()
+	if err := JSON(&buf, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Total map[string]any `json:"total"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, k := range []string{"cost_per_active_hour", "tokens_per_active_minute", "output_input_ratio"} {
+		if _, ok := result.Total[k]; !ok {
+			t.Errorf("expected %q in total: %v", k, result.Total)
+		}
+	}
+	if v := result.Total["cost_per_active_hour"].(float64); v != roundCost(rpt.Total.Cost/2) {
+		t.Errorf("expected cost per hour %.2f, got %v", rpt.Total.Cost/2, v)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	rpt := sampleReport()
+	var buf bytes.Buffer
+	if err := RowsNDJSON(&buf, &rpt); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(rpt.Rows)+1 {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(rpt.Rows)+1, len(lines), buf.String())
+	}
+	var total map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &total); err != nil || total["key"] != "TOTAL" {
+		t.Errorf("expected a TOTAL object last, got %s (%v)", lines[len(lines)-1], err)
+	}
+
+	records := []parser.Record{
+		{ID: "m1", Time: time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", CWD: "/src/shop", Session: "s1", Input: 1_000_000, WebFetches: 1, User: "alice"},
+		{ID: "m2", Time: time.Date(2026, 2, 1, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "blog", Session: "s2", Output: 10},
+	}
+	buf.Reset()
+	if err := RecordsNDJSON(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	var got []map[string]any
+	for dec.More() {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, obj)
+	}
+	if len(got) != 2 || got[0]["id"] != "m1" || got[0]["cwd"] != "/src/shop" || got[0]["user"] != "alice" ||
+		got[0]["web_fetch_requests"] != 1.0 || got[0]["cost"] != 1.0 {
+		t.Errorf("unexpected records: %v", got)
+	}
+}
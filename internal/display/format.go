@@ -0,0 +1,22 @@
+package display
+
+import "time"
+
+// Tokens formats a token count the same way Table does: compact (1.2M) unless
+// exact is set.
+func Tokens(n int, exact bool) string {
+	if exact {
+		return formatNum(n)
+	}
+	return formatCompact(n)
+}
+
+// Cost formats a USD amount, or "N/A" for a negative (unknown) cost.
+func Cost(c float64) string {
+	return formatCost(c)
+}
+
+// Duration formats a session duration as 1h05m; zero renders empty.
+func Duration(d time.Duration) string {
+	return formatDuration(d)
+}
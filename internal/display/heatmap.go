@@ -0,0 +1,114 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// Heatmap shades, from empty to peak. Each cell is drawn two columns wide.
+var (
+	heatUnicode = []string{"··", "░░", "▒▒", "▓▓", "██"}
+	heatASCII   = []string{"..", "--", "++", "**", "##"}
+	heatColors  = []text.Colors{
+		{text.Faint},
+		{text.FgBlue},
+		{text.FgCyan},
+		{text.FgYellow},
+		{text.FgRed},
+	}
+)
+
+// HeatmapGrid writes a weekday × hour grid to w, shading each cell relative
+// to the busiest hour, with a total per weekday.
+func HeatmapGrid(w io.Writer, h *report.Heatmap, title string, exact, ascii bool) {
+	shades := heatUnicode
+	if ascii {
+		shades = heatASCII
+	}
+	format := func(v float64) string {
+		if h.Metric == report.MetricCost {
+			return formatCost(v)
+		}
+		if exact {
+			return formatNum(int(v))
+		}
+		return formatCompact(int(v))
+	}
+
+	if title != "" {
+		_, _ = fmt.Fprintln(w, text.FgCyan.Sprint(title))
+	}
+
+	var b strings.Builder
+	b.WriteString("     ")
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(&b, "%02d    ", hour)
+	}
+	_, _ = fmt.Fprintln(w, text.FgCyan.Sprint(strings.TrimRight(b.String(), " ")))
+
+	peak := h.Max()
+	for day, wd := range report.Weekdays {
+		b.Reset()
+		b.WriteString(wd.String()[:3] + "  ")
+		for _, v := range h.Cells[day] {
+			level := 0
+			if v > 0 && peak > 0 {
+				level = max(1, int(math.Ceil(v/peak*float64(len(shades)-1))))
+			}
+			b.WriteString(heatColors[level].Sprint(shades[level]))
+		}
+		fmt.Fprintf(&b, "  %s", format(h.RowTotal(day)))
+		_, _ = fmt.Fprintln(w, b.String())
+	}
+
+	b.Reset()
+	b.WriteString("     ")
+	for i, s := range shades {
+		fmt.Fprintf(&b, "%s ", heatColors[i].Sprint(s))
+	}
+	fmt.Fprintf(&b, " 0 → %s per hour", format(peak))
+	_, _ = fmt.Fprintln(w, b.String())
+}
+
+type jsonHeatmap struct {
+	Metric   string      `json:"metric"`
+	Weekdays []string    `json:"weekdays"`
+	Hours    []int       `json:"hours"`
+	Values   [][]float64 `json:"values"`
+}
+
+// HeatmapJSON writes the heatmap as a weekday × hour matrix to w. Costs are
+// rounded to cents.
+func HeatmapJSON(w io.Writer, h *report.Heatmap) error {
+	jh := jsonHeatmap{
+		Metric: string(h.Metric),
+		Hours:  make([]int, 24),
+		Values: make([][]float64, len(report.Weekdays)),
+	}
+	for i := range jh.Hours {
+		jh.Hours[i] = i
+	}
+	for day, wd := range report.Weekdays {
+		jh.Weekdays = append(jh.Weekdays, wd.String())
+		jh.Values[day] = make([]float64, 24)
+		for hour, v := range h.Cells[day] {
+			if h.Metric == report.MetricCost {
+				v = roundCost(v)
+			}
+			jh.Values[day][hour] = v
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(jh); err != nil {
+		return fmt.Errorf("encoding heatmap: %w", err)
+	}
+	return nil
+}
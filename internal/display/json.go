@@ -17,12 +17,58 @@ type jsonRow struct {
 	CacheWrite      int     `json:"cache_write_tokens"`
 	CacheRead       int     `json:"cache_read_tokens"`
 	DurationSeconds int     `json:"duration_seconds,omitempty"`
+	ActiveSeconds   int     `json:"active_seconds,omitempty"`
 	Cost            float64 `json:"cost"`
+
+	WebSearches    int     `json:"web_search_requests"`
+	WebFetches     int     `json:"web_fetch_requests"`
+	ServerToolCost float64 `json:"server_tool_cost"`
+
+	CacheHitRatio     float64 `json:"cache_hit_ratio"`
+	CacheSaved        float64 `json:"cache_saved"`
+	CacheWritePremium float64 `json:"cache_write_premium"`
+	CacheROI          float64 `json:"cache_roi"`
+
+	MainCost  float64 `json:"main_cost"`
+	AgentCost float64 `json:"agent_cost"`
+	Agents    int     `json:"agents"`
+	ToolCalls int     `json:"tool_calls"`
+
+	CostPerHour     float64 `json:"cost_per_active_hour"`
+	TokensPerMinute float64 `json:"tokens_per_active_minute"`
+	OutputRatio     float64 `json:"output_input_ratio"`
+
+	Children []jsonRow `json:"children,omitempty"`
+
+	CumCost   *float64 `json:"cumulative_cost,omitempty"`
+	CumTokens int      `json:"cumulative_tokens,omitempty"`
+}
+
+type jsonCumulative struct {
+	Date   string  `json:"date"`
+	Cost   float64 `json:"cost"`
+	Tokens int     `json:"tokens"`
+}
+
+type jsonPlanMonth struct {
+	Month         string  `json:"month"`
+	Users         int     `json:"users"`
+	Cost          float64 `json:"api_cost"`
+	PerUser       float64 `json:"api_cost_per_user"`
+	Fee           float64 `json:"plan_fee"`
+	ValueMultiple float64 `json:"value_multiple"`
+	BreakEven     string  `json:"break_even,omitempty"`
+	Days          int     `json:"days_covered"`
+	MonthDays     int     `json:"days_in_month"`
 }
 
 type jsonReport struct {
-	Rows  []jsonRow `json:"rows"`
-	Total jsonRow   `json:"total"`
+	Dims  []string        `json:"dimensions,omitempty"`
+	Rows  []jsonRow       `json:"rows"`
+	Total jsonRow         `json:"total"`
+	Plan  []jsonPlanMonth `json:"plan,omitempty"`
+
+	Cumulative []jsonCumulative `json:"cumulative,omitempty"`
 }
 
 func roundCost(c float64) float64 {
@@ -32,32 +78,87 @@ func roundCost(c float64) float64 {
 	return math.Round(c*100) / 100
 }
 
+// roundSigned rounds an amount that may legitimately be negative to cents.
+func roundSigned(c float64) float64 {
+	return math.Round(c*100) / 100
+}
+
+func newJSONRow(r *report.Row) jsonRow {
+	jr := jsonRow{
+		Key:               r.Key,
+		Model:             r.Model,
+		Input:             r.Input,
+		Output:            r.Output,
+		CacheWrite:        r.CacheWrite,
+		CacheRead:         r.CacheRead,
+		Cost:              roundCost(r.Cost),
+		DurationSeconds:   int(r.Duration.Seconds()),
+		ActiveSeconds:     int(r.Active.Seconds()),
+		WebSearches:       r.WebSearches,
+		WebFetches:        r.WebFetches,
+		ServerToolCost:    roundCost(r.ServerToolCost),
+		CacheHitRatio:     math.Round(r.CacheHitRatio()*10000) / 10000,
+		CacheSaved:        roundCost(r.CacheSaved),
+		CacheWritePremium: roundCost(r.CacheWritePremium),
+		CacheROI:          roundSigned(r.CacheROI()),
+		MainCost:          roundCost(r.MainCost()),
+		AgentCost:         roundCost(r.AgentCost),
+		Agents:            r.Agents,
+		ToolCalls:         r.ToolCalls,
+		CostPerHour:       roundCost(r.CostPerHour),
+		TokensPerMinute:   math.Round(r.TokensPerMinute*10) / 10,
+		OutputRatio:       math.Round(r.OutputRatio*10000) / 10000,
+		Children:          newJSONRows(r.Children),
+	}
+	if r.CumTokens > 0 {
+		c := roundCost(r.CumCost)
+		jr.CumCost = &c
+		jr.CumTokens = r.CumTokens
+	}
+	return jr
+}
+
+func newJSONRows(rows []report.Row) []jsonRow {
+	if len(rows) == 0 {
+		return nil
+	}
+	out := make([]jsonRow, len(rows))
+	for i := range rows {
+		out[i] = newJSONRow(&rows[i])
+	}
+	return out
+}
+
 // JSON writes the report as JSON to w.
 func JSON(w io.Writer, rpt *report.Report) error {
 	jr := jsonReport{
-		Rows: make([]jsonRow, len(rpt.Rows)),
-		Total: jsonRow{
-			Key:             rpt.Total.Key,
-			Input:           rpt.Total.Input,
-			Output:          rpt.Total.Output,
-			CacheWrite:      rpt.Total.CacheWrite,
-			CacheRead:       rpt.Total.CacheRead,
-			Cost:            roundCost(rpt.Total.Cost),
-			DurationSeconds: int(rpt.Total.Duration.Seconds()),
-		},
+		Rows:  make([]jsonRow, len(rpt.Rows)),
+		Total: newJSONRow(&rpt.Total),
+	}
+
+	for i := range rpt.Rows {
+		jr.Rows[i] = newJSONRow(&rpt.Rows[i])
+	}
+	for _, c := range rpt.Cumulative {
+		jr.Cumulative = append(jr.Cumulative, jsonCumulative{Date: c.Date, Cost: roundCost(c.Cost), Tokens: c.Tokens})
+	}
+	for _, d := range rpt.Dims {
+		jr.Dims = append(jr.Dims, string(d))
 	}
 
-	for i, r := range rpt.Rows {
-		jr.Rows[i] = jsonRow{
-			Key:             r.Key,
-			Model:           r.Model,
-			Input:           r.Input,
-			Output:          r.Output,
-			CacheWrite:      r.CacheWrite,
-			CacheRead:       r.CacheRead,
-			Cost:            roundCost(r.Cost),
-			DurationSeconds: int(r.Duration.Seconds()),
-		}
+	for i := range rpt.Plan {
+		m := &rpt.Plan[i]
+		jr.Plan = append(jr.Plan, jsonPlanMonth{
+			Month:         m.Month,
+			Users:         m.Users,
+			Cost:          roundCost(m.Cost),
+			PerUser:       roundCost(m.PerUser()),
+			Fee:           roundCost(m.Fee),
+			ValueMultiple: math.Round(m.Multiple()*100) / 100,
+			BreakEven:     m.BreakEven,
+			Days:          m.Days,
+			MonthDays:     m.MonthDays,
+		})
 	}
 
 	enc := json.NewEncoder(w)
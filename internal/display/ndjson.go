@@ -0,0 +1,38 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// RecordsNDJSON writes one JSON object per record to w, each on its own
+// line. Fields match RecordsJSON.
+func RecordsNDJSON(w io.Writer, records []parser.Record) error {
+	enc := json.NewEncoder(w)
+	for i := range records {
+		if err := enc.Encode(newJSONRecord(&records[i])); err != nil {
+			return fmt.Errorf("encoding record: %w", err)
+		}
+	}
+	return nil
+}
+
+// RowsNDJSON writes one JSON object per report row to w, followed by the
+// total (key "TOTAL"). Fields match the rows of JSON; nested rows carry
+// their children inline.
+func RowsNDJSON(w io.Writer, rpt *report.Report) error {
+	enc := json.NewEncoder(w)
+	for i := range rpt.Rows {
+		if err := enc.Encode(newJSONRow(&rpt.Rows[i])); err != nil {
+			return fmt.Errorf("encoding row: %w", err)
+		}
+	}
+	if err := enc.Encode(newJSONRow(&rpt.Total)); err != nil {
+		return fmt.Errorf("encoding total: %w", err)
+	}
+	return nil
+}
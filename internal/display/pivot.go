@@ -0,0 +1,196 @@
+package display
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// pivotLabel shortens a pivot key for display: models lose the "claude-"
+// prefix and dates their year when all share one.
+func pivotLabel(dim report.Dimension, key string, trimYear bool) string {
+	switch {
+	case dim == report.Model:
+		return strings.TrimPrefix(key, "claude-")
+	case trimYear:
+		return trimDate(key, false)
+	}
+	return key
+}
+
+func singleYear(keys []string) bool {
+	years := map[string]bool{}
+	for _, k := range keys {
+		years[yearOf(k)] = true
+	}
+	return len(years) <= 1
+}
+
+// PivotTable writes the pivot as a matrix with row and column totals to w.
+func PivotTable(w io.Writer, p *report.Pivot, title string, exact bool) {
+	format := func(v float64) string {
+		if p.Metric == report.MetricCost {
+			return formatCost(v)
+		}
+		if exact {
+			return formatNum(int(v))
+		}
+		return formatCompact(int(v))
+	}
+
+	tw := table.NewWriter()
+	tw.SetOutputMirror(w)
+	if title != "" {
+		tw.SetTitle(text.FgCyan.Sprint(title))
+	}
+
+	trimCols := singleYear(p.ColKeys)
+	header := make(table.Row, 0, len(p.ColKeys)+2)
+	header = append(header, p.Rows.Header()+" \\ "+p.Cols.Header())
+	for _, k := range p.ColKeys {
+		header = append(header, pivotLabel(p.Cols, k, trimCols))
+	}
+	tw.AppendHeader(append(header, "Total"))
+
+	trimRows := singleYear(p.RowKeys)
+	for i, k := range p.RowKeys {
+		row := table.Row{pivotLabel(p.Rows, k, trimRows)}
+		for _, v := range p.Values[i] {
+			cell := ""
+			if v != 0 {
+				cell = format(v)
+			}
+			row = append(row, cell)
+		}
+		tw.AppendRow(append(row, format(p.RowTotals[i])))
+	}
+
+	footer := make(table.Row, 0, len(p.ColTotals)+2)
+	footer = append(footer, "TOTAL")
+	for _, v := range p.ColTotals {
+		footer = append(footer, format(v))
+	}
+	tw.AppendFooter(append(footer, format(p.Total)))
+
+	var colConfigs []table.ColumnConfig
+	for i := 2; i <= len(p.ColKeys)+2; i++ {
+		colConfigs = append(colConfigs, table.ColumnConfig{
+			Number:      i,
+			Align:       text.AlignRight,
+			AlignHeader: text.AlignRight,
+			AlignFooter: text.AlignRight,
+		})
+	}
+	tw.SetColumnConfigs(colConfigs)
+
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Format.Footer = text.FormatDefault
+	tw.Style().Color.Header = text.Colors{text.FgCyan}
+	tw.Style().Color.Footer = text.Colors{text.FgYellow}
+	tw.Style().Options.DoNotColorBordersAndSeparators = true
+
+	rowIdx := 0
+	tw.SetRowPainter(func(table.Row) text.Colors {
+		rowIdx++
+		if rowIdx%2 == 0 {
+			return text.Colors{text.Faint}
+		}
+		return nil
+	})
+
+	tw.Render()
+}
+
+// pivotValue rounds costs to cents; token counts are whole already.
+func pivotValue(p *report.Pivot, v float64) float64 {
+	if p.Metric == report.MetricCost {
+		return roundCost(v)
+	}
+	return v
+}
+
+// PivotCSV writes the pivot to w as CSV: a header of column keys, one line
+// per row key, and a final total line. Unknown costs are left empty.
+func PivotCSV(w io.Writer, p *report.Pivot) error {
+	cell := func(v float64) string {
+		if v < 0 {
+			return ""
+		}
+		return strconv.FormatFloat(pivotValue(p, v), 'f', -1, 64)
+	}
+
+	cw := csv.NewWriter(w)
+	header := append([]string{string(p.Rows) + `\` + string(p.Cols)}, p.ColKeys...)
+	lines := make([][]string, 0, len(p.RowKeys)+2)
+	lines = append(lines, append(header, "total"))
+	for i, k := range p.RowKeys {
+		line := []string{k}
+		for _, v := range p.Values[i] {
+			line = append(line, cell(v))
+		}
+		lines = append(lines, append(line, cell(p.RowTotals[i])))
+	}
+	total := make([]string, 0, len(p.ColTotals)+2)
+	total = append(total, "total")
+	for _, v := range p.ColTotals {
+		total = append(total, cell(v))
+	}
+	lines = append(lines, append(total, cell(p.Total)))
+
+	if err := cw.WriteAll(lines); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
+
+type jsonPivot struct {
+	Rows         string      `json:"rows"`
+	Columns      string      `json:"columns"`
+	Metric       string      `json:"metric"`
+	RowKeys      []string    `json:"row_keys"`
+	ColumnKeys   []string    `json:"column_keys"`
+	Values       [][]float64 `json:"values"`
+	RowTotals    []float64   `json:"row_totals"`
+	ColumnTotals []float64   `json:"column_totals"`
+	Total        float64     `json:"total"`
+}
+
+// PivotJSON writes the pivot matrix with its totals to w. Costs are rounded
+// to cents; unknown costs are -1.
+func PivotJSON(w io.Writer, p *report.Pivot) error {
+	round := func(vs []float64) []float64 {
+		out := make([]float64, len(vs))
+		for i, v := range vs {
+			out[i] = pivotValue(p, v)
+		}
+		return out
+	}
+	jp := jsonPivot{
+		Rows:         string(p.Rows),
+		Columns:      string(p.Cols),
+		Metric:       string(p.Metric),
+		RowKeys:      p.RowKeys,
+		ColumnKeys:   p.ColKeys,
+		Values:       make([][]float64, len(p.Values)),
+		RowTotals:    round(p.RowTotals),
+		ColumnTotals: round(p.ColTotals),
+		Total:        pivotValue(p, p.Total),
+	}
+	for i := range p.Values {
+		jp.Values[i] = round(p.Values[i])
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(jp); err != nil {
+		return fmt.Errorf("encoding pivot: %w", err)
+	}
+	return nil
+}
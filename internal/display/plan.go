@@ -0,0 +1,68 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// PlanTable writes the subscription comparison to w: API-equivalent cost
+// against plan fees per month, the value multiple and the break-even day.
+func PlanTable(w io.Writer, months []report.PlanMonth, title string) {
+	if len(months) == 0 {
+		return
+	}
+
+	tw := table.NewWriter()
+	tw.SetOutputMirror(w)
+	if title != "" {
+		tw.SetTitle(text.FgCyan.Sprint(title))
+	}
+	tw.AppendHeader(table.Row{"Month", "Users", "API cost", "Per user", "Plan fee", "Value", "Break-even"})
+
+	for i := range months {
+		m := &months[i]
+		// Green when the plan beat API pricing for the month.
+		value := fmt.Sprintf("%.1f×", m.Multiple())
+		if m.Multiple() >= 1 {
+			value = text.FgGreen.Sprint(value)
+		}
+		breakEven := "—"
+		if t, err := time.Parse("2006-01-02", m.BreakEven); err == nil {
+			breakEven = t.Format("Jan 02")
+		}
+		month := m.Month
+		if m.Partial() {
+			month += fmt.Sprintf(" (%d/%d days)", m.Days, m.MonthDays)
+		}
+		tw.AppendRow(table.Row{
+			month,
+			m.Users,
+			formatCost(m.Cost),
+			formatCost(m.PerUser()),
+			formatCost(m.Fee),
+			value,
+			breakEven,
+		})
+	}
+
+	var colConfigs []table.ColumnConfig
+	for i := 2; i <= 7; i++ {
+		colConfigs = append(colConfigs, table.ColumnConfig{
+			Number:      i,
+			Align:       text.AlignRight,
+			AlignHeader: text.AlignRight,
+		})
+	}
+	tw.SetColumnConfigs(colConfigs)
+
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Color.Header = text.Colors{text.FgCyan}
+	tw.Style().Options.DoNotColorBordersAndSeparators = true
+
+	tw.Render()
+}
@@ -0,0 +1,211 @@
+package display
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// shortID trims session IDs (UUIDs) to their first block for tables.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// RecordsTable writes one line per record to w, with a total footer.
+func RecordsTable(w io.Writer, records []parser.Record, title string, exact bool) {
+	fmtTok := formatCompact
+	if exact {
+		fmtTok = formatNum
+	}
+
+	tw := table.NewWriter()
+	tw.SetOutputMirror(w)
+	if title != "" {
+		tw.SetTitle(text.FgCyan.Sprint(title))
+	}
+	tw.AppendHeader(table.Row{"Time", "Project", "Session", "Model", "Input", "Output", "Write", "Read", "Cost"})
+
+	var total report.Row
+	unknown := false
+	for i := range records {
+		r := &records[i]
+		c := report.RecordCost(r)
+		if c < 0 {
+			unknown = true
+		} else {
+			total.Cost += c
+		}
+		total.Input += r.Input
+		total.Output += r.Output
+		total.CacheWrite += r.CacheWrite
+		total.CacheRead += r.CacheRead
+
+		tw.AppendRow(table.Row{
+			r.Time.Format("2006-01-02 15:04"),
+			r.Project,
+			shortID(r.Session),
+			strings.TrimPrefix(r.Model, "claude-"),
+			fmtTok(r.Input),
+			fmtTok(r.Output),
+			fmtTok(r.CacheWrite),
+			fmtTok(r.CacheRead),
+			formatCost(c),
+		})
+	}
+	if unknown {
+		total.Cost = -1
+	}
+	tw.AppendFooter(table.Row{
+		fmt.Sprintf("TOTAL (%d)", len(records)), "", "", "",
+		fmtTok(total.Input),
+		fmtTok(total.Output),
+		fmtTok(total.CacheWrite),
+		fmtTok(total.CacheRead),
+		formatCost(total.Cost),
+	})
+
+	var colConfigs []table.ColumnConfig
+	for i := 5; i <= 9; i++ {
+		colConfigs = append(colConfigs, table.ColumnConfig{
+			Number:      i,
+			Align:       text.AlignRight,
+			AlignHeader: text.AlignRight,
+			AlignFooter: text.AlignRight,
+		})
+	}
+	tw.SetColumnConfigs(colConfigs)
+
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Format.Footer = text.FormatDefault
+	tw.Style().Color.Header = text.Colors{text.FgCyan}
+	tw.Style().Color.Footer = text.Colors{text.FgYellow}
+	tw.Style().Options.DoNotColorBordersAndSeparators = true
+
+	rowIdx := 0
+	tw.SetRowPainter(func(table.Row) text.Colors {
+		rowIdx++
+		if rowIdx%2 == 0 {
+			return text.Colors{text.Faint}
+		}
+		return nil
+	})
+
+	tw.Render()
+}
+
+type jsonRecord struct {
+	ID          string   `json:"id,omitempty"`
+	Time        string   `json:"time"`
+	Project     string   `json:"project"`
+	CWD         string   `json:"cwd,omitempty"`
+	Repo        string   `json:"repo,omitempty"`
+	Branch      string   `json:"branch,omitempty"`
+	Session     string   `json:"session"`
+	Agent       string   `json:"agent,omitempty"`
+	User        string   `json:"user,omitempty"`
+	Model       string   `json:"model"`
+	Input       int      `json:"input_tokens"`
+	Output      int      `json:"output_tokens"`
+	CacheWrite  int      `json:"cache_write_tokens"`
+	CacheRead   int      `json:"cache_read_tokens"`
+	Tools       []string `json:"tools,omitempty"`
+	WebSearches int      `json:"web_search_requests,omitempty"`
+	WebFetches  int      `json:"web_fetch_requests,omitempty"`
+	Cost        float64  `json:"cost"`
+}
+
+func newJSONRecord(r *parser.Record) jsonRecord {
+	return jsonRecord{
+		ID:          r.ID,
+		Time:        r.Time.Format(time.RFC3339),
+		Project:     r.Project,
+		CWD:         r.CWD,
+		Repo:        r.Repo,
+		Branch:      r.Branch,
+		Session:     r.Session,
+		Agent:       r.AgentID,
+		User:        r.User,
+		Model:       r.Model,
+		Input:       r.Input,
+		Output:      r.Output,
+		CacheWrite:  r.CacheWrite,
+		CacheRead:   r.CacheRead,
+		Tools:       r.Tools,
+		WebSearches: r.WebSearches,
+		WebFetches:  r.WebFetches,
+		Cost:        roundCost(report.RecordCost(r)),
+	}
+}
+
+// RecordsJSON writes records as a JSON array to w. Times are RFC 3339 in
+// the records' time zone.
+func RecordsJSON(w io.Writer, records []parser.Record) error {
+	out := make([]jsonRecord, len(records))
+	for i := range records {
+		out[i] = newJSONRecord(&records[i])
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("encoding records: %w", err)
+	}
+	return nil
+}
+
+// recordsCSVHeader lists the CSV columns written by RecordsCSV.
+var recordsCSVHeader = []string{
+	"time", "project", "repo", "branch", "session", "agent", "model",
+	"input_tokens", "output_tokens", "cache_write_tokens", "cache_read_tokens",
+	"web_search_requests", "cost",
+}
+
+// RecordsCSV writes records as CSV with a header line to w. Unknown costs
+// are left empty.
+func RecordsCSV(w io.Writer, records []parser.Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(recordsCSVHeader); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	for i := range records {
+		r := &records[i]
+		cost := ""
+		if c := report.RecordCost(r); c >= 0 {
+			cost = strconv.FormatFloat(c, 'f', 6, 64)
+		}
+		row := []string{
+			r.Time.Format(time.RFC3339),
+			r.Project,
+			r.Repo,
+			r.Branch,
+			r.Session,
+			r.AgentID,
+			r.Model,
+			strconv.Itoa(r.Input),
+			strconv.Itoa(r.Output),
+			strconv.Itoa(r.CacheWrite),
+			strconv.Itoa(r.CacheRead),
+			strconv.Itoa(r.WebSearches),
+			cost,
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("writing CSV: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("writing CSV: %w", err)
+	}
+	return nil
+}
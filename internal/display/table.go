@@ -52,6 +52,18 @@ func formatCost(cost float64) string {
 	return fmt.Sprintf("$%.2f", cost)
 }
 
+func formatPercent(ratio float64) string {
+	return fmt.Sprintf("%.0f%%", ratio*100)
+}
+
+// formatSignedCost formats a cost that may be negative (e.g. net savings).
+func formatSignedCost(c float64) string {
+	if c < 0 {
+		return fmt.Sprintf("-$%.2f", -c)
+	}
+	return fmt.Sprintf("$%.2f", c)
+}
+
 func formatDuration(d time.Duration) string {
 	if d == 0 {
 		return ""
@@ -61,68 +73,208 @@ func formatDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh%02dm", h, m)
 }
 
-// trimDate removes the "YYYY-" prefix from date-shaped keys.
-// Non-date keys are returned as-is.
+// trimDate removes the "YYYY-" prefix from date keys (YYYY-MM-DD). Other
+// keys, such as a branch named "feat-login", are returned as-is.
 func trimDate(key string, weekday bool) string {
-	if len(key) > 4 && key[4] == '-' {
-		s := key[5:]
-		if weekday {
-			if t, err := time.Parse("2006-01-02", key); err == nil {
-				s = t.Format("Mon") + " " + s
-			}
-		}
-		return s
+	t, err := time.Parse("2006-01-02", key)
+	if err != nil {
+		return key
 	}
-	return key
+	s := key[5:]
+	if weekday {
+		s = t.Format("Mon") + " " + s
+	}
+	return s
 }
 
-// yearOf extracts the "YYYY" prefix from a date-shaped key, or "".
+// yearOf extracts the "YYYY" prefix from a date key, or "".
 func yearOf(key string) string {
-	if len(key) > 4 && key[4] == '-' {
-		return key[:4]
+	if _, err := time.Parse("2006-01-02", key); err != nil {
+		return ""
 	}
-	return ""
+	return key[:4]
 }
 
-// Table writes a formatted table to w.
-// When exact is true, token counts are shown as full numbers (1,234,567);
-// otherwise they use compact notation (1.2M, 34.5K).
-func Table(w io.Writer, rpt *report.Report, keyHeader string, exact bool, title string) {
+// TableOptions controls Table rendering.
+type TableOptions struct {
+	KeyHeader  string // header of the key column, e.g. "Date" or "Project"
+	Title      string
+	Exact      bool // full token counts (1,234,567) instead of compact (1.2M)
+	Cache      bool // append cache efficiency columns
+	Agents     bool // append main-session vs subagent cost columns
+	Calls      bool // append a tool call count column
+	Active     bool // show active session time next to Time
+	Rates      bool // append cost per active hour, tokens per minute and output/input ratio columns
+	Cumulative bool // append running-total cost and token columns
+}
+
+// column is a right-aligned metric column.
+type column struct {
+	header string
+	value  func(r *report.Row) string
+}
+
+// metricColumns returns the metric columns for opts, in display order. The
+// server tool fee column is included only when server is set.
+func metricColumns(opts *TableOptions, server bool) []column {
 	fmtTok := formatCompact
-	if exact {
+	if opts.Exact {
 		fmtTok = formatNum
 	}
+	cols := []column{
+		{"Input", func(r *report.Row) string { return fmtTok(r.Input) }},
+		{"Output", func(r *report.Row) string { return fmtTok(r.Output) }},
+		{"Write", func(r *report.Row) string { return fmtTok(r.CacheWrite) }},
+		{"Read", func(r *report.Row) string { return fmtTok(r.CacheRead) }},
+	}
+	if server {
+		cols = append(cols, column{"Server", func(r *report.Row) string { return formatCost(r.ServerToolCost) }})
+	}
+	cols = append(cols,
+		column{"Time", func(r *report.Row) string { return formatDuration(r.Duration) }},
+	)
+	if opts.Active {
+		cols = append(cols, column{"Active", func(r *report.Row) string { return formatDuration(r.Active) }})
+	}
+	cols = append(cols, column{"Cost", func(r *report.Row) string { return formatCost(r.Cost) }})
+	if opts.Cumulative {
+		cols = append(cols,
+			column{"Cum. tokens", func(r *report.Row) string {
+				if r.CumTokens == 0 {
+					return ""
+				}
+				return fmtTok(r.CumTokens)
+			}},
+			column{"Cum. cost", func(r *report.Row) string {
+				if r.CumTokens == 0 {
+					return ""
+				}
+				return formatCost(r.CumCost)
+			}},
+		)
+	}
+	if opts.Rates {
+		cols = append(cols,
+			column{"$/h", func(r *report.Row) string {
+				if r.TokensPerMinute == 0 {
+					return ""
+				}
+				return formatCost(r.CostPerHour)
+			}},
+			column{"Tok/min", func(r *report.Row) string {
+				if r.TokensPerMinute == 0 {
+					return ""
+				}
+				return fmtTok(int(r.TokensPerMinute + 0.5))
+			}},
+			column{"Out/In", func(r *report.Row) string {
+				if r.Input == 0 {
+					return ""
+				}
+				return strconv.FormatFloat(r.OutputRatio, 'f', 2, 64)
+			}},
+		)
+	}
+	if opts.Calls {
+		cols = append(cols, column{"Calls", func(r *report.Row) string { return formatNum(r.ToolCalls) }})
+	}
+	if opts.Cache {
+		cols = append(cols,
+			column{"Hit", func(r *report.Row) string { return formatPercent(r.CacheHitRatio()) }},
+			column{"Saved", func(r *report.Row) string { return formatCost(r.CacheSaved) }},
+			column{"ROI", func(r *report.Row) string { return formatSignedCost(r.CacheROI()) }},
+		)
+	}
+	if opts.Agents {
+		cols = append(cols,
+			column{"Main", func(r *report.Row) string { return formatCost(r.MainCost()) }},
+			column{"Agents", func(r *report.Row) string { return formatCost(r.AgentCost) }},
+			column{"Agent %", func(r *report.Row) string {
+				if r.Cost <= 0 {
+					return ""
+				}
+				return formatPercent(r.AgentCost / r.Cost)
+			}},
+			column{"Spawned", func(r *report.Row) string { return strconv.Itoa(r.Agents) }},
+		)
+	}
+	return cols
+}
+
+// Table writes a formatted table to w.
+// Token counts use compact notation (1.2M, 34.5K) unless opts.Exact is set.
+// A Server column with web search/fetch fees appears when any were charged.
+// Nested reports render each level as rows indented under their subtotal.
+func Table(w io.Writer, rpt *report.Report, opts TableOptions) {
+	cols := metricColumns(&opts, rpt.Total.ServerToolCost > 0)
+	values := func(r *report.Row) table.Row {
+		out := make(table.Row, len(cols))
+		for i, c := range cols {
+			out[i] = c.value(r)
+		}
+		return out
+	}
 
 	tw := table.NewWriter()
 	tw.SetOutputMirror(w)
 
-	weekly := strings.HasPrefix(title, "Weekly")
-	if title != "" {
-		tw.SetTitle(text.FgCyan.Sprint(title))
+	weekly := strings.HasPrefix(opts.Title, "Weekly")
+	if opts.Title != "" {
+		tw.SetTitle(text.FgCyan.Sprint(opts.Title))
 	}
 
 	showModel := slices.ContainsFunc(rpt.Rows, func(r report.Row) bool { return r.Model != "" })
 
-	displayHeader := keyHeader
-
+	header := table.Row{opts.KeyHeader}
 	if showModel {
-		tw.AppendHeader(table.Row{displayHeader, "Model", "Input", "Output", "Write", "Read", "Time", "Cost"})
-	} else {
-		tw.AppendHeader(table.Row{displayHeader, "Input", "Output", "Write", "Read", "Time", "Cost"})
+		header = append(header, "Model")
+	}
+	keyCols := len(header)
+	for _, c := range cols {
+		header = append(header, c.header)
 	}
+	tw.AppendHeader(header)
 
 	years := make(map[string]bool)
-	for _, row := range rpt.Rows {
-		if y := yearOf(row.Key); y != "" {
-			years[y] = true
+	var collectYears func(rows []report.Row)
+	collectYears = func(rows []report.Row) {
+		for i := range rows {
+			if y := yearOf(rows[i].Key); y != "" {
+				years[y] = true
+			}
+			collectYears(rows[i].Children)
 		}
 	}
+	collectYears(rpt.Rows)
 	multiYear := len(years) > 1
 
-	if showModel {
+	nested := len(rpt.Dims) > 1
+	var depths []int // nesting level of each appended row, for the painter
+	switch {
+	case nested:
+		var appendTree func(rows []report.Row, depth int)
+		appendTree = func(rows []report.Row, depth int) {
+			for i := range rows {
+				row := &rows[i]
+				key := row.Key
+				switch {
+				case rpt.Dims[depth] == report.Model:
+					key = strings.TrimPrefix(key, "claude-")
+				case !multiYear:
+					key = trimDate(key, weekly && rpt.Dims[depth] == report.Date)
+				}
+				key = strings.Repeat("  ", depth) + key
+				tw.AppendRow(append(table.Row{key}, values(row)...))
+				depths = append(depths, depth)
+				appendTree(row.Children, depth+1)
+			}
+		}
+		appendTree(rpt.Rows, 0)
+	case showModel:
 		prevKey := ""
 		prevYear := ""
-		for i, row := range rpt.Rows {
+		for i := range rpt.Rows {
+			row := &rpt.Rows[i]
 			y := yearOf(row.Key)
 			if multiYear && y != "" && y != prevYear {
 				if i > 0 {
@@ -141,67 +293,31 @@ func Table(w io.Writer, rpt *report.Report, keyHeader string, exact bool, title
 			}
 			prevKey = row.Key
 
-			tw.AppendRow(table.Row{
-				displayKey,
-				strings.TrimPrefix(row.Model, "claude-"),
-				fmtTok(row.Input),
-				fmtTok(row.Output),
-				fmtTok(row.CacheWrite),
-				fmtTok(row.CacheRead),
-				formatDuration(row.Duration),
-				formatCost(row.Cost),
-			})
+			tw.AppendRow(append(table.Row{displayKey, strings.TrimPrefix(row.Model, "claude-")}, values(row)...))
 		}
-	} else {
+	default:
 		prevYear := ""
-		for _, row := range rpt.Rows {
+		for i := range rpt.Rows {
+			row := &rpt.Rows[i]
 			if y := yearOf(row.Key); y != "" {
 				if multiYear && y != prevYear {
 					tw.AppendRow(table.Row{y}, table.RowConfig{AutoMerge: true})
 				}
 				prevYear = y
 			}
-			tw.AppendRow(table.Row{
-				trimDate(row.Key, weekly),
-				fmtTok(row.Input),
-				fmtTok(row.Output),
-				fmtTok(row.CacheWrite),
-				fmtTok(row.CacheRead),
-				formatDuration(row.Duration),
-				formatCost(row.Cost),
-			})
+			tw.AppendRow(append(table.Row{trimDate(row.Key, weekly)}, values(row)...))
 		}
 	}
 
+	footer := table.Row{"TOTAL"}
 	if showModel {
-		tw.AppendFooter(table.Row{
-			"TOTAL", "",
-			fmtTok(rpt.Total.Input),
-			fmtTok(rpt.Total.Output),
-			fmtTok(rpt.Total.CacheWrite),
-			fmtTok(rpt.Total.CacheRead),
-			formatDuration(rpt.Total.Duration),
-			formatCost(rpt.Total.Cost),
-		})
-	} else {
-		tw.AppendFooter(table.Row{
-			"TOTAL",
-			fmtTok(rpt.Total.Input),
-			fmtTok(rpt.Total.Output),
-			fmtTok(rpt.Total.CacheWrite),
-			fmtTok(rpt.Total.CacheRead),
-			formatDuration(rpt.Total.Duration),
-			formatCost(rpt.Total.Cost),
-		})
+		footer = append(footer, "")
 	}
+	tw.AppendFooter(append(footer, values(&rpt.Total)...))
 
-	// Right-align numeric columns. Column numbers shift when Model is present.
-	numStart := 2
-	if showModel {
-		numStart = 3
-	}
+	// Right-align metric columns, which follow the key (and Model) columns.
 	var colConfigs []table.ColumnConfig
-	for i := numStart; i <= numStart+5; i++ {
+	for i := keyCols + 1; i <= keyCols+len(cols); i++ {
 		colConfigs = append(colConfigs, table.ColumnConfig{
 			Number:      i,
 			Align:       text.AlignRight,
@@ -225,14 +341,32 @@ func Table(w io.Writer, rpt *report.Report, keyHeader string, exact bool, title
 		return ok && len(s) == 4 && s[0] >= '1' && s[0] <= '9'
 	}
 
-	if showModel {
+	switch {
+	case nested:
+		rowIdx := 0
+		leaf := len(rpt.Dims) - 1
+		tw.SetRowPainter(func(table.Row) text.Colors {
+			if rowIdx >= len(depths) {
+				return nil
+			}
+			depth := depths[rowIdx]
+			rowIdx++
+			switch depth {
+			case 0:
+				return text.Colors{text.Bold}
+			case leaf:
+				return text.Colors{text.Faint}
+			}
+			return nil
+		})
+	case showModel:
 		tw.SetRowPainter(func(row table.Row) text.Colors {
 			if isYearRow(row) {
 				return text.Colors{text.FgCyan}
 			}
 			return nil
 		})
-	} else {
+	default:
 		rowIdx := 0
 		tw.SetRowPainter(func(row table.Row) text.Colors {
 			if isYearRow(row) {
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repoInfo describes the git repository enclosing a working directory.
+type repoInfo struct {
+	root   string // worktree-independent repository root
+	remote string // normalized origin URL, e.g. github.com/org/repo; may be empty
+}
+
+// key identifies the repository across clones: the remote when known,
+// otherwise the local root.
+func (r repoInfo) key() string {
+	if r.remote != "" {
+		return r.remote
+	}
+	return r.root
+}
+
+// findRepo walks up from cwd looking for a .git entry. Linked worktrees are
+// resolved to their main repository so they share one root. It returns false
+// when cwd is not inside a repository or no longer exists on disk; a deleted
+// project must not inherit a repository enclosing it, such as a tracked home
+// directory.
+func findRepo(cwd string) (repoInfo, bool) {
+	if _, err := os.Stat(cwd); err != nil {
+		return repoInfo{}, false
+	}
+	for dir := filepath.Clean(cwd); ; dir = filepath.Dir(dir) {
+		gitPath := filepath.Join(dir, ".git")
+		fi, err := os.Stat(gitPath)
+		if err == nil {
+			gitDir := gitPath
+			root := dir
+			if !fi.IsDir() {
+				gitDir = readGitFile(gitPath, dir)
+				// Linked worktree: gitdir is <root>/.git/worktrees/<name>.
+				if i := strings.LastIndex(gitDir, string(filepath.Separator)+filepath.Join(".git", "worktrees")); i >= 0 {
+					root = gitDir[:i]
+					gitDir = filepath.Join(root, ".git")
+				}
+			}
+			return repoInfo{root: root, remote: readRemote(filepath.Join(gitDir, "config"))}, true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return repoInfo{}, false
+		}
+	}
+}
+
+// readGitFile returns the directory referenced by a "gitdir: <path>" file.
+func readGitFile(path, dir string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return filepath.Clean(gitDir)
+}
+
+// readRemote returns the normalized URL of the "origin" remote, or of the
+// first remote when there is no origin.
+func readRemote(configPath string) string {
+	f, err := os.Open(configPath)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+
+	var section, first, origin string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "url" || !strings.HasPrefix(section, "[remote ") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if first == "" {
+			first = value
+		}
+		if section == `[remote "origin"]` {
+			origin = value
+		}
+	}
+	if origin == "" {
+		origin = first
+	}
+	return normalizeRemote(origin)
+}
+
+// normalizeRemote reduces ssh and https remote URLs to host/path form so that
+// different clones of the same repository compare equal.
+func normalizeRemote(url string) string {
+	if url == "" {
+		return ""
+	}
+	if _, rest, ok := strings.Cut(url, "://"); ok {
+		url = rest
+		if _, hostPath, ok := strings.Cut(url, "@"); ok {
+			url = hostPath
+		}
+	} else if _, hostPath, ok := strings.Cut(url, "@"); ok {
+		// scp-like syntax: git@host:org/repo.git
+		url = strings.Replace(hostPath, ":", "/", 1)
+	}
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+	return url
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindRepo(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "shop")
+	writeFile(t, filepath.Join(root, ".git", "config"), `[core]
+	bare = false
+[remote "upstream"]
+	url = https://github.com/upstream/shop.git
+[remote "origin"]
+	url = git@github.com:acme/shop.git
+`)
+	sub := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	wt := filepath.Join(base, "shop-feature")
+	writeFile(t, filepath.Join(wt, ".git"), "gitdir: "+filepath.Join(root, ".git", "worktrees", "shop-feature")+"\n")
+
+	for _, cwd := range []string{root, sub, wt} {
+		info, ok := findRepo(cwd)
+		if !ok {
+			t.Fatalf("findRepo(%q): expected repository", cwd)
+		}
+		if info.root != root {
+			t.Errorf("findRepo(%q): root = %q, want %q", cwd, info.root, root)
+		}
+		if info.remote != "github.com/acme/shop" {
+			t.Errorf("findRepo(%q): remote = %q, want github.com/acme/shop", cwd, info.remote)
+		}
+	}
+
+	if _, ok := findRepo(filepath.Join(base, "gone")); ok {
+		t.Error("expected no repository for directory outside a repo")
+	}
+}
+
+func TestFindRepoDeletedCWD(t *testing.T) {
+	home := t.TempDir()
+	writeFile(t, filepath.Join(home, ".git", "config"), "[remote \"origin\"]\n\turl = git@github.com:me/dotfiles.git\n")
+
+	if _, ok := findRepo(home); !ok {
+		t.Fatal("expected the home directory to be a repository")
+	}
+	// A removed project under the tracked home must not inherit its repo.
+	if info, ok := findRepo(filepath.Join(home, "src", "removed")); ok {
+		t.Errorf("expected no repository for a deleted cwd, got %+v", info)
+	}
+}
+
+func TestNormalizeRemote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"git@github.com:acme/shop.git", "github.com/acme/shop"},
+		{"https://github.com/acme/shop.git", "github.com/acme/shop"},
+		{"https://user@gitlab.com/acme/shop/", "gitlab.com/acme/shop"},
+		{"ssh://git@github.com/acme/shop", "github.com/acme/shop"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeRemote(tt.in); got != tt.want {
+			t.Errorf("normalizeRemote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRepoAndBranch(t *testing.T) {
+	base := t.TempDir()
+	clone1 := filepath.Join(base, "a", "shop")
+	clone2 := filepath.Join(base, "b", "shop-old")
+	remote := "[remote \"origin\"]\n\turl = git@github.com:acme/shop.git\n"
+	writeFile(t, filepath.Join(clone1, ".git", "config"), remote)
+	writeFile(t, filepath.Join(clone2, ".git", "config"), remote)
+	web := filepath.Join(clone1, "web")
+	if err := os.MkdirAll(web, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "p1", "s1.jsonl"), `{"type":"user","timestamp":"2026-02-14T09:00:00.000Z","cwd":"`+web+`","gitBranch":"main"}
+{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"`+web+`","gitBranch":"feat/ABC-12","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`)
+	writeFile(t, filepath.Join(dir, "p2", "s2.jsonl"), `{"type":"assistant","timestamp":"2026-02-14T11:00:00.000Z","cwd":"`+clone2+`","gitBranch":"main","message":{"id":"msg_002","model":"claude-opus-4-6","usage":{"input_tokens":200,"output_tokens":100,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`)
+	writeFile(t, filepath.Join(dir, "p3", "s3.jsonl"), `{"type":"assistant","timestamp":"2026-02-14T12:00:00.000Z","cwd":"/nonexistent/scratch","message":{"id":"msg_003","model":"claude-opus-4-6","usage":{"input_tokens":300,"output_tokens":150,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`)
+
+	records, sessions, _, err := parseDir(dir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+
+	want := map[int]struct{ project, repo, branch string }{
+		100: {"web", "shop", "feat/ABC-12"},
+		200: {"shop-old", "shop", "main"},
+		300: {"scratch", "scratch", ""},
+	}
+	for _, r := range records {
+		w := want[r.Input]
+		if r.Project != w.project || r.Repo != w.repo || r.Branch != w.branch {
+			t.Errorf("record %d: got project=%q repo=%q branch=%q, want %+v", r.Input, r.Project, r.Repo, r.Branch, w)
+		}
+	}
+
+	// The hour before the switch to feat/ABC-12 was spent on main.
+	webTime := map[string]time.Duration{}
+	for _, s := range sessions {
+		if s.Project == "web" {
+			webTime[s.Branch] = s.Duration
+		}
+		if s.Project == "web" && s.Repo != "shop" {
+			t.Errorf("expected session repo 'shop', got %q", s.Repo)
+		}
+	}
+	if d, ok := webTime["feat/ABC-12"]; len(webTime) != 2 || webTime["main"] != time.Hour || !ok || d != 0 {
+		t.Errorf("expected 1h on main and none on feat/ABC-12, got %v", webTime)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"slices"
 	"strings"
@@ -18,53 +19,246 @@ import (
 )
 
 type Usage struct {
-	InputTokens              int `json:"input_tokens"`
-	OutputTokens             int `json:"output_tokens"`
-	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
-	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	InputTokens              int           `json:"input_tokens"`
+	OutputTokens             int           `json:"output_tokens"`
+	CacheCreationInputTokens int           `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int           `json:"cache_read_input_tokens"`
+	ServerToolUse            ServerToolUse `json:"server_tool_use"`
+}
+
+// ServerToolUse counts requests to tools executed by the API, which are
+// billed per request.
+type ServerToolUse struct {
+	WebSearchRequests int `json:"web_search_requests"`
+	WebFetchRequests  int `json:"web_fetch_requests"`
 }
 
 func (u Usage) IsZero() bool {
 	return u.InputTokens == 0 && u.OutputTokens == 0 &&
-		u.CacheCreationInputTokens == 0 && u.CacheReadInputTokens == 0
+		u.CacheCreationInputTokens == 0 && u.CacheReadInputTokens == 0 &&
+		u.ServerToolUse.WebSearchRequests == 0 && u.ServerToolUse.WebFetchRequests == 0
 }
 
 type Message struct {
 	ID    string `json:"id"`
 	Model string `json:"model"`
 	Usage Usage  `json:"usage"`
+
+	// Content is kept raw: it is a string in user messages and a list of
+	// blocks in assistant messages.
+	Content json.RawMessage `json:"content"`
+}
+
+// ContentBlock is one element of an assistant message's content. Only
+// tool_use blocks are of interest.
+type ContentBlock struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// toolUses returns the tool_use blocks in raw message content.
+func toolUses(raw json.RawMessage) []ContentBlock {
+	if len(raw) == 0 || raw[0] != '[' {
+		return nil
+	}
+	var blocks []ContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil
+	}
+	return slices.DeleteFunc(blocks, func(b ContentBlock) bool { return b.Type != "tool_use" || b.Name == "" })
 }
 
 type Entry struct {
 	Type      string  `json:"type"`
 	Timestamp string  `json:"timestamp"`
 	CWD       string  `json:"cwd"`
+	GitBranch string  `json:"gitBranch"`
 	Message   Message `json:"message"`
 }
 
 // Record is a deduplicated assistant entry with parsed time.
+// Time is expressed in Options.Location.
 type Record struct {
+	ID         string // API message ID, unique across machines
 	Time       time.Time
 	Model      string
 	Project    string
+	CWD        string   // working directory of the session
+	Repo       string   // enclosing git repository; falls back to Project
+	Branch     string   // git branch at the time of the request; may be empty
+	Session    string   // session ID, shared by a main log and its subagents
+	IsSubagent bool     // request made by a subagent (Task tool) rather than the main session
+	AgentID    string   // subagent log name, e.g. "agent-a1b2c3"; empty for the main session
+	Tools      []string // tools invoked by the response, one entry per call
 	Input      int
 	Output     int
 	CacheWrite int
 	CacheRead  int
+
+	WebSearches int // server-side web search requests
+	WebFetches  int // server-side web fetch requests
+
+	User string // user label of the bundle the record was merged from; empty for local logs
 }
 
 // Session represents time spent in a main session file on a single day.
 // A session spanning multiple days produces one Session per day.
 type Session struct {
+	ID       string
 	Date     string // YYYY-MM-DD
 	Project  string
-	Duration time.Duration
+	Repo     string
+	CWD      string
+	Branch   string        // git branch the time was spent on; may be empty
+	Duration time.Duration // wall-clock time on Branch between the day's first and last entry
+	Active   time.Duration // sum of gaps between entries shorter than the idle threshold
+	User     string        // user label of the bundle the session was merged from
 }
 
 type Options struct {
-	Since   time.Time
-	Until   time.Time
-	Project string // substring match
+	Since    time.Time
+	Until    time.Time
+	Project  Filter
+	Model    Filter         // matched against normalized model names
+	Branch   string         // substring match on git branch
+	Location *time.Location // zone for timestamps and day buckets; nil means time.Local
+	Aliases  []Alias        // applied in order; first match wins
+	Idle     time.Duration  // gaps longer than this don't count as active time; zero means DefaultIdle
+}
+
+// DefaultIdle is the default idle threshold for active session time.
+const DefaultIdle = 15 * time.Minute
+
+func (o *Options) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+func (o *Options) idle() time.Duration {
+	if o.Idle <= 0 {
+		return DefaultIdle
+	}
+	return o.Idle
+}
+
+// Filter selects names by case-insensitive substrings and regular
+// expressions. A name passes when it matches any Include substring or Regex
+// (or neither is set) and no Exclude substring. The zero Filter passes
+// everything.
+type Filter struct {
+	Include []string
+	Exclude []string
+	Regex   []*regexp.Regexp
+}
+
+// Match reports whether name passes the filter.
+func (f *Filter) Match(name string) bool {
+	lower := strings.ToLower(name)
+	for _, s := range f.Exclude {
+		if strings.Contains(lower, strings.ToLower(s)) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 && len(f.Regex) == 0 {
+		return true
+	}
+	for _, s := range f.Include {
+		if strings.Contains(lower, strings.ToLower(s)) {
+			return true
+		}
+	}
+	for _, re := range f.Regex {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// set reports whether the filter can exclude anything.
+func (f *Filter) set() bool {
+	return len(f.Include) > 0 || len(f.Exclude) > 0 || len(f.Regex) > 0
+}
+
+// sessionsWithRecords drops session days that have no records left, so
+// session time follows a filter applied to records. Record times must
+// already be in the location sessions were dated in.
+func sessionsWithRecords(sessions []Session, records []Record) []Session {
+	days := map[[2]string]bool{}
+	for i := range records {
+		days[[2]string{records[i].Session, records[i].Time.Format("2006-01-02")}] = true
+	}
+	return slices.DeleteFunc(sessions, func(s Session) bool {
+		return !days[[2]string{s.ID, s.Date}]
+	})
+}
+
+// Alias assigns a fixed project name to every cwd matching a pattern, so
+// worktrees, monorepo subdirectories and renamed checkouts roll up together.
+type Alias struct {
+	Name string
+	glob string
+	re   *regexp.Regexp
+}
+
+// ParseAlias parses a "PATTERN=NAME" rule. PATTERN is a filepath glob matched
+// against the cwd and each of its parent directories (a leading "~/" expands
+// to the home directory), or a regular expression matched against the cwd when
+// prefixed with "re:".
+func ParseAlias(rule string) (Alias, error) {
+	i := strings.LastIndex(rule, "=")
+	if i <= 0 || i == len(rule)-1 {
+		return Alias{}, fmt.Errorf("alias %q: expected PATTERN=NAME", rule)
+	}
+	pattern, name := rule[:i], rule[i+1:]
+
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return Alias{}, fmt.Errorf("alias %q: %w", rule, err)
+		}
+		return Alias{Name: name, re: re}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(pattern, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Alias{}, fmt.Errorf("getting home directory: %w", err)
+		}
+		pattern = filepath.Join(home, rest)
+	}
+	pattern = filepath.Clean(pattern)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return Alias{}, fmt.Errorf("alias %q: %w", rule, err)
+	}
+	return Alias{Name: name, glob: pattern}, nil
+}
+
+// Match reports whether cwd is covered by the alias.
+func (a *Alias) Match(cwd string) bool {
+	if a.re != nil {
+		return a.re.MatchString(cwd)
+	}
+	for p := filepath.Clean(cwd); ; p = filepath.Dir(p) {
+		if ok, _ := filepath.Match(a.glob, p); ok {
+			return true
+		}
+		if parent := filepath.Dir(p); parent == p {
+			return false
+		}
+	}
+}
+
+func matchAlias(aliases []Alias, cwd string) (string, bool) {
+	for i := range aliases {
+		if aliases[i].Match(cwd) {
+			return aliases[i].Name, true
+		}
+	}
+	return "", false
 }
 
 func claudeDir() (string, error) {
@@ -76,7 +270,7 @@ func claudeDir() (string, error) {
 }
 
 // Parse reads all JSONL files under ~/.claude/projects and returns deduplicated records and sessions.
-func Parse(opts Options) ([]Record, []Session, []string, error) {
+func Parse(opts *Options) ([]Record, []Session, []string, error) {
 	dir, err := claudeDir()
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("finding claude directory: %w", err)
@@ -84,6 +278,19 @@ func Parse(opts Options) ([]Record, []Session, []string, error) {
 	return parseDir(dir, opts)
 }
 
+// Stream reads the same files as Parse, but passes each file's records to
+// fn, in time order, as soon as that file is parsed instead of collecting
+// them all, so output can start before the last file is read. Files come
+// in no particular order. Session time is not computed. It returns Parse's
+// warnings, or stops at the first error from fn and returns it.
+func Stream(opts *Options, fn func([]Record) error) ([]string, error) {
+	dir, err := claudeDir()
+	if err != nil {
+		return nil, fmt.Errorf("finding claude directory: %w", err)
+	}
+	return streamDir(dir, opts, fn)
+}
+
 type fileJob struct {
 	path   string
 	isMain bool
@@ -97,7 +304,8 @@ type fileResult struct {
 	err      error  // non-nil if parseFile failed
 }
 
-func parseDir(dir string, opts Options) ([]Record, []Session, []string, error) {
+// logFiles lists the main session and subagent files under dir.
+func logFiles(dir string) ([]fileJob, error) {
 	// Main session files: <project>/<uuid>.jsonl
 	mainPattern := filepath.Join(dir, "*", "*.jsonl")
 	// Subagent files: <project>/<uuid>/subagents/agent-*.jsonl
@@ -105,7 +313,7 @@ func parseDir(dir string, opts Options) ([]Record, []Session, []string, error) {
 
 	mainFiles, err := filepath.Glob(mainPattern)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("globbing session files: %w", err)
+		return nil, fmt.Errorf("globbing session files: %w", err)
 	}
 	// Pattern is hardcoded; filepath.Glob only errors on malformed patterns.
 	subFiles, _ := filepath.Glob(subPattern)
@@ -117,70 +325,77 @@ func parseDir(dir string, opts Options) ([]Record, []Session, []string, error) {
 	for _, f := range subFiles {
 		jobs = append(jobs, fileJob{path: f, isMain: false})
 	}
+	return jobs, nil
+}
 
-	results := make([]fileResult, len(jobs))
-	workers := min(runtime.NumCPU(), len(jobs))
-
-	var wg sync.WaitGroup
+// parseFiles parses jobs on all CPUs into results and sends the index of
+// each file on the returned channel once its result is filled in. The
+// channel is closed when every file is done. Closing quit stops the
+// workers before their next file; a nil quit never does.
+func parseFiles(jobs []fileJob, opts *Options, results []fileResult, quit <-chan struct{}) <-chan int {
 	ch := make(chan int, len(jobs))
 	for i := range jobs {
 		ch <- i
 	}
 	close(ch)
 
-	for range workers {
+	done := make(chan int, len(jobs))
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(jobs)) {
 		wg.Go(func() {
 			for i := range ch {
+				select {
+				case <-quit:
+					return
+				default:
+				}
 				records, sessions, unknown, cwd, err := parseFile(jobs[i].path, opts, jobs[i].isMain)
 				if err != nil {
 					results[i] = fileResult{err: err}
-					continue
+				} else {
+					results[i] = fileResult{records: records, sessions: sessions, unknown: unknown, cwd: cwd}
 				}
-				results[i] = fileResult{records: records, sessions: sessions, unknown: unknown, cwd: cwd}
+				done <- i
 			}
 		})
 	}
-	wg.Wait()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
 
-	// Build baseName → set of unique full CWDs for disambiguation.
-	cwdsByBase := map[string]map[string]bool{}
-	for _, r := range results {
-		if r.cwd == "" {
-			continue
-		}
-		base := filepath.Base(r.cwd)
-		if cwdsByBase[base] == nil {
-			cwdsByBase[base] = map[string]bool{}
-		}
-		cwdsByBase[base][r.cwd] = true
+func parseDir(dir string, opts *Options) ([]Record, []Session, []string, error) {
+	jobs, err := logFiles(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	results := make([]fileResult, len(jobs))
+	for range parseFiles(jobs, opts, results, nil) {
+	}
+
+	cwds := make([]string, len(results))
+	for i := range results {
+		cwds[i] = results[i].cwd
 	}
-	displayNames := disambiguateProjects(cwdsByBase)
+	displayNames, repoNames := projectNames(cwds, opts)
 
-	// Merge results: apply disambiguated project names and project filter.
+	// Merge results: apply disambiguated project names and filters.
 	var allRecords []Record
-	projectFilter := strings.ToLower(opts.Project)
 	var allSessions []Session
 	var fileErrors []string
 	unknownModels := map[string]bool{}
 
-	for _, r := range results {
+	for i := range results {
+		r := &results[i]
 		if r.err != nil {
 			fileErrors = append(fileErrors, "skipped file: "+r.err.Error())
 			continue
 		}
-		name := displayNames[r.cwd] // empty for files with no CWD
-
-		if projectFilter != "" && !strings.Contains(strings.ToLower(name), projectFilter) {
+		if !selectFile(r, displayNames[r.cwd], repoNames[r.cwd], opts) {
 			continue
 		}
-
-		for i := range r.records {
-			r.records[i].Project = name
-		}
-		for i := range r.sessions {
-			r.sessions[i].Project = name
-		}
-
 		allRecords = append(allRecords, r.records...)
 		allSessions = append(allSessions, r.sessions...)
 		for _, m := range r.unknown {
@@ -188,6 +403,12 @@ func parseDir(dir string, opts Options) ([]Record, []Session, []string, error) {
 		}
 	}
 
+	// Sessions span files (subagent records share their parent's session),
+	// so they are matched against the model filter once everything is merged.
+	if opts.Model.set() {
+		allSessions = sessionsWithRecords(allSessions, allRecords)
+	}
+
 	slices.SortFunc(allRecords, func(a, b Record) int {
 		return a.Time.Compare(b.Time)
 	})
@@ -196,18 +417,143 @@ func parseDir(dir string, opts Options) ([]Record, []Session, []string, error) {
 		return cmp.Compare(a.Date, b.Date)
 	})
 
+	return allRecords, allSessions, warnings(fileErrors, unknownModels), nil
+}
+
+// streamDir is Stream for the logs under dir. Project names depend on every
+// CWD, so each file's CWD is read up front, which takes only its first
+// lines.
+func streamDir(dir string, opts *Options, fn func([]Record) error) ([]string, error) {
+	jobs, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	cwds := make([]string, len(jobs))
+	for i := range jobs {
+		cwds[i] = firstCWD(jobs[i].path)
+	}
+	displayNames, repoNames := projectNames(cwds, opts)
+
+	var fileErrors []string
+	unknownModels := map[string]bool{}
+	results := make([]fileResult, len(jobs))
+	quit := make(chan struct{})
+	defer close(quit)
+	for i := range parseFiles(jobs, opts, results, quit) {
+		r := &results[i]
+		if r.err != nil {
+			fileErrors = append(fileErrors, "skipped file: "+r.err.Error())
+			continue
+		}
+		if selectFile(r, displayNames[r.cwd], repoNames[r.cwd], opts) && len(r.records) > 0 {
+			slices.SortFunc(r.records, func(a, b Record) int {
+				return a.Time.Compare(b.Time)
+			})
+			if err := fn(r.records); err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range r.unknown {
+			unknownModels[m] = true
+		}
+		results[i] = fileResult{} // written out: let it be collected
+	}
+	return warnings(fileErrors, unknownModels), nil
+}
+
+// firstCWD returns the first working directory recorded in the log file at
+// path, as parseFile does, or "" if it has none or can't be read.
+func firstCWD(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var e struct {
+			CWD string `json:"cwd"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && e.CWD != "" {
+			return filepath.Clean(e.CWD)
+		}
+	}
+	return ""
+}
+
+// projectNames maps each CWD to its project display name and repository
+// name. Alias rules take precedence; remaining CWDs are grouped by
+// baseName → set of unique full CWDs for disambiguation.
+func projectNames(cwds []string, opts *Options) (names, repos map[string]string) {
+	aliased := map[string]string{}
+	cwdsByBase := map[string]map[string]bool{}
+	for _, cwd := range cwds {
+		if cwd == "" {
+			continue
+		}
+		if name, ok := matchAlias(opts.Aliases, cwd); ok {
+			aliased[cwd] = name
+			continue
+		}
+		base := filepath.Base(cwd)
+		if cwdsByBase[base] == nil {
+			cwdsByBase[base] = map[string]bool{}
+		}
+		cwdsByBase[base][cwd] = true
+	}
+	names = disambiguateProjects(cwdsByBase)
+	maps.Copy(names, aliased)
+	return names, resolveRepos(cwds, names)
+}
+
+// selectFile labels a parsed file's records and sessions with its project,
+// repository and CWD, and applies the project, model and branch filters.
+// It reports false if the project is filtered out.
+func selectFile(r *fileResult, name, repo string, opts *Options) bool {
+	// name is empty for files with no CWD.
+	if !opts.Project.Match(name) {
+		return false
+	}
+
+	for i := range r.records {
+		r.records[i].Project = name
+		r.records[i].Repo = repo
+		r.records[i].CWD = r.cwd
+	}
+	for i := range r.sessions {
+		r.sessions[i].Project = name
+		r.sessions[i].Repo = repo
+		r.sessions[i].CWD = r.cwd
+	}
+
+	r.records = slices.DeleteFunc(r.records, func(rec Record) bool {
+		return !opts.Model.Match(rec.Model)
+	})
+	if branchFilter := strings.ToLower(opts.Branch); branchFilter != "" {
+		r.records = slices.DeleteFunc(r.records, func(rec Record) bool {
+			return !strings.Contains(strings.ToLower(rec.Branch), branchFilter)
+		})
+		r.sessions = slices.DeleteFunc(r.sessions, func(s Session) bool {
+			return !strings.Contains(strings.ToLower(s.Branch), branchFilter)
+		})
+	}
+	return true
+}
+
+// warnings lists skipped files, then unknown models, each sorted.
+func warnings(fileErrors []string, unknownModels map[string]bool) []string {
 	slices.Sort(fileErrors)
-	var warnings []string
-	warnings = append(warnings, fileErrors...)
+	out := make([]string, 0, len(fileErrors)+len(unknownModels))
+	out = append(out, fileErrors...)
 	for m := range unknownModels {
-		warnings = append(warnings, "unknown model: "+m)
+		out = append(out, "unknown model: "+m)
 	}
-	slices.Sort(warnings[len(fileErrors):])
-
-	return allRecords, allSessions, warnings, nil
+	slices.Sort(out[len(fileErrors):])
+	return out
 }
 
-func parseTime(s string) (time.Time, bool) {
+func parseTime(s string, loc *time.Location) (time.Time, bool) {
 	t, err := time.Parse(time.RFC3339Nano, s)
 	if err != nil {
 		t, err = time.Parse(time.RFC3339, s)
@@ -215,26 +561,85 @@ func parseTime(s string) (time.Time, bool) {
 			return time.Time{}, false
 		}
 	}
-	return t.Local(), true
+	return t.In(loc), true
+}
+
+// stamp is the time and git branch of one log entry, for session time.
+type stamp struct {
+	t      time.Time
+	branch string
+}
+
+// branchTime is the session time spent on one branch during a day.
+type branchTime struct {
+	branch   string
+	duration time.Duration
+	active   time.Duration
 }
 
-// dayBounds tracks min/max timestamps for a single day.
-type dayBounds struct {
-	min, max time.Time
+// splitByBranch divides a day's session time between the branches its
+// entries were on. Each gap between consecutive entries counts towards the
+// branch of the earlier entry, so the branches' durations add up to the
+// day's span from first to last entry; gaps longer than idle are left out
+// of active time. Entries without a branch take the one before them, or
+// the day's first branch. Branches are returned in order of first use.
+func splitByBranch(stamps []stamp, idle time.Duration) []branchTime {
+	slices.SortStableFunc(stamps, func(x, y stamp) int { return x.t.Compare(y.t) })
+	branch := ""
+	for i := range stamps {
+		if stamps[i].branch != "" {
+			branch = stamps[i].branch
+			break
+		}
+	}
+
+	var out []branchTime
+	index := map[string]int{}
+	for i := range stamps {
+		if stamps[i].branch == "" {
+			stamps[i].branch = branch
+		}
+		branch = stamps[i].branch
+		if _, ok := index[branch]; !ok {
+			index[branch] = len(out)
+			out = append(out, branchTime{branch: branch})
+		}
+		if i == 0 {
+			continue
+		}
+		bt := &out[index[stamps[i-1].branch]]
+		gap := stamps[i].t.Sub(stamps[i-1].t)
+		bt.duration += gap
+		if gap <= idle {
+			bt.active += gap
+		}
+	}
+	return out
 }
 
-func parseFile(path string, opts Options, isMain bool) ([]Record, []Session, []string, string, error) { //nolint:gocritic // unnamedResult: 5 returns is intentional for this internal function
+func parseFile(path string, opts *Options, isMain bool) ([]Record, []Session, []string, string, error) { //nolint:gocritic // unnamedResult: 5 returns is intentional for this internal function
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, nil, nil, "", fmt.Errorf("opening log file: %w", err)
 	}
 	defer func() { _ = f.Close() }()
 
+	loc := opts.location()
+	sessionID := sessionIDFromPath(path, isMain)
+	agentID := ""
+	if !isMain {
+		agentID = strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	}
+
 	// First pass: collect entries, deduplicate by message.id (keep max output_tokens).
+	// A streamed message is logged once per content block, so tool calls are
+	// collected across all of its entries.
 	// Also track min/max timestamps per day for session duration (main files only).
-	best := map[string]Entry{}
+	best := map[string]*Entry{}
+	tools := map[string][]string{} // message.id → tool names
+	seenTools := map[string]bool{} // tool_use IDs already counted
 	var fullCWD string
-	days := map[string]*dayBounds{} // date string → bounds
+	days := map[string][]stamp{} // date string → entry times and branches
 
 	scanner := bufio.NewScanner(f)
 	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
@@ -251,32 +656,31 @@ func parseFile(path string, opts Options, isMain bool) ([]Record, []Session, []s
 
 		// Track timestamps per day for session duration.
 		if isMain && e.Timestamp != "" {
-			if t, ok := parseTime(e.Timestamp); ok {
+			if t, ok := parseTime(e.Timestamp, loc); ok {
 				day := t.Format("2006-01-02")
-				b, exists := days[day]
-				if !exists {
-					b = &dayBounds{min: t, max: t}
-					days[day] = b
-				} else {
-					if t.Before(b.min) {
-						b.min = t
-					}
-					if t.After(b.max) {
-						b.max = t
-					}
-				}
+				days[day] = append(days[day], stamp{t, e.GitBranch})
 			}
 		}
 
 		if e.Type != "assistant" || e.Message.ID == "" {
 			continue
 		}
+		for _, b := range toolUses(e.Message.Content) {
+			if b.ID != "" {
+				if seenTools[b.ID] {
+					continue
+				}
+				seenTools[b.ID] = true
+			}
+			tools[e.Message.ID] = append(tools[e.Message.ID], b.Name)
+		}
+		e.Message.Content = nil
 		if prev, ok := best[e.Message.ID]; ok {
 			if e.Message.Usage.OutputTokens > prev.Message.Usage.OutputTokens {
-				best[e.Message.ID] = e
+				best[e.Message.ID] = &e
 			}
 		} else {
-			best[e.Message.ID] = e
+			best[e.Message.ID] = &e
 		}
 	}
 	if err := scanner.Err(); err != nil {
@@ -286,13 +690,13 @@ func parseFile(path string, opts Options, isMain bool) ([]Record, []Session, []s
 	var records []Record
 	unknownModels := map[string]bool{}
 
-	for _, e := range best {
+	for id, e := range best {
 		// Skip entries with all-zero usage (e.g. <synthetic>).
 		if e.Message.Usage.IsZero() {
 			continue
 		}
 
-		t, ok := parseTime(e.Timestamp)
+		t, ok := parseTime(e.Timestamp, loc)
 		if !ok {
 			continue
 		}
@@ -312,30 +716,44 @@ func parseFile(path string, opts Options, isMain bool) ([]Record, []Session, []s
 		}
 
 		records = append(records, Record{
+			ID:         id,
 			Time:       t,
 			Model:      normalized,
+			Branch:     e.GitBranch,
+			Session:    sessionID,
+			IsSubagent: !isMain,
+			AgentID:    agentID,
+			Tools:      tools[id],
 			Input:      e.Message.Usage.InputTokens,
 			Output:     e.Message.Usage.OutputTokens,
 			CacheWrite: e.Message.Usage.CacheCreationInputTokens,
 			CacheRead:  e.Message.Usage.CacheReadInputTokens,
+
+			WebSearches: e.Message.Usage.ServerToolUse.WebSearchRequests,
+			WebFetches:  e.Message.Usage.ServerToolUse.WebFetchRequests,
 		})
 	}
 
-	// Build per-day sessions for main files.
+	// Build per-day sessions for main files, one per branch worked on.
 	var sessions []Session
 	if isMain && fullCWD != "" {
-		for date, b := range days {
-			day, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+		for date, stamps := range days {
+			day, _ := time.ParseInLocation("2006-01-02", date, loc)
 			if !opts.Since.IsZero() && day.Before(opts.Since) {
 				continue
 			}
 			if !opts.Until.IsZero() && day.After(opts.Until) {
 				continue
 			}
-			sessions = append(sessions, Session{
-				Date:     date,
-				Duration: b.max.Sub(b.min),
-			})
+			for _, bt := range splitByBranch(stamps, opts.idle()) {
+				sessions = append(sessions, Session{
+					ID:       sessionID,
+					Date:     date,
+					Branch:   bt.branch,
+					Duration: bt.duration,
+					Active:   bt.active,
+				})
+			}
 		}
 	}
 
@@ -344,6 +762,55 @@ func parseFile(path string, opts Options, isMain bool) ([]Record, []Session, []s
 	return records, sessions, warnings, fullCWD, nil
 }
 
+// sessionIDFromPath derives the session ID from a log path: the file name of
+// a main log (<uuid>.jsonl), or the directory owning a subagent log
+// (<uuid>/subagents/agent-*.jsonl).
+func sessionIDFromPath(path string, isMain bool) string {
+	if isMain {
+		return strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	}
+	return filepath.Base(filepath.Dir(filepath.Dir(path)))
+}
+
+// resolveRepos maps each CWD to a repository display name. CWDs inside the
+// same git repository (including linked worktrees and clones sharing a remote)
+// get the same name; CWDs outside any repository fall back to their project name.
+func resolveRepos(cwds []string, projectNames map[string]string) map[string]string {
+	keyByCWD := map[string]string{}
+	keysByBase := map[string]map[string]bool{}
+	for _, cwd := range cwds {
+		if cwd == "" {
+			continue
+		}
+		if _, done := keyByCWD[cwd]; done {
+			continue
+		}
+		info, ok := findRepo(cwd)
+		if !ok {
+			keyByCWD[cwd] = ""
+			continue
+		}
+		key := info.key()
+		keyByCWD[cwd] = key
+		base := lastNComponents(key, 1)
+		if keysByBase[base] == nil {
+			keysByBase[base] = map[string]bool{}
+		}
+		keysByBase[base][key] = true
+	}
+	names := disambiguateProjects(keysByBase)
+
+	result := make(map[string]string, len(keyByCWD))
+	for cwd, key := range keyByCWD {
+		if key == "" {
+			result[cwd] = projectNames[cwd]
+			continue
+		}
+		result[cwd] = names[key]
+	}
+	return result
+}
+
 // disambiguateProjects resolves collisions where multiple CWDs share the same
 // filepath.Base() name. For unique base names, the base name is used. For
 // collisions, parent path components are added until names are unique.
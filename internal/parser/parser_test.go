@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"testing"
@@ -28,7 +30,7 @@ func TestParseBasic(t *testing.T) {
 {"type":"assistant","timestamp":"2026-02-14T10:02:00.000Z","cwd":"/home/user/myproject","message":{"id":"msg_002","model":"claude-opus-4-6","usage":{"input_tokens":150,"output_tokens":75,"cache_creation_input_tokens":0,"cache_read_input_tokens":500}}}
 `
 	dir := setupTestDir(t, data)
-	records, _, warnings, err := parseDir(dir, Options{})
+	records, _, warnings, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,7 +50,7 @@ func TestDeduplication(t *testing.T) {
 {"type":"assistant","timestamp":"2026-02-14T10:00:01.000Z","cwd":"/home/user/proj","message":{"id":"msg_dup","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
 `
 	dir := setupTestDir(t, data)
-	records, _, _, err := parseDir(dir, Options{})
+	records, _, _, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -66,7 +68,7 @@ func TestFilterSince(t *testing.T) {
 `
 	dir := setupTestDir(t, data)
 	since, _ := time.Parse("2006-01-02", "2026-02-12")
-	records, _, _, err := parseDir(dir, Options{Since: since})
+	records, _, _, err := parseDir(dir, &Options{Since: since})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -83,7 +85,7 @@ func TestFilterProject(t *testing.T) {
 `
 	dir := setupTestDir(t, data)
 
-	records, _, _, err := parseDir(dir, Options{Project: "myapp"})
+	records, _, _, err := parseDir(dir, &Options{Project: Filter{Include: []string{"myapp"}}})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -91,7 +93,7 @@ func TestFilterProject(t *testing.T) {
 		t.Errorf("expected 1 record matching 'myapp', got %d", len(records))
 	}
 
-	records, _, _, err = parseDir(dir, Options{Project: "other"})
+	records, _, _, err = parseDir(dir, &Options{Project: Filter{Include: []string{"other"}}})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,7 +106,7 @@ func TestUnknownModel(t *testing.T) {
 	data := `{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-future-99","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
 `
 	dir := setupTestDir(t, data)
-	_, _, warnings, err := parseDir(dir, Options{})
+	_, _, warnings, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -118,7 +120,7 @@ func TestZeroTokensSkipped(t *testing.T) {
 {"type":"assistant","timestamp":"2026-02-14T10:01:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_real","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
 `
 	dir := setupTestDir(t, data)
-	records, _, warnings, err := parseDir(dir, Options{})
+	records, _, warnings, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -155,7 +157,7 @@ func TestSubagentFiles(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	records, sessions, warnings, err := parseDir(dir, Options{})
+	records, sessions, warnings, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -171,6 +173,19 @@ func TestSubagentFiles(t *testing.T) {
 		t.Error("expected subagent with haiku model to be parsed")
 	}
 
+	for _, r := range records {
+		sub := r.Model == "claude-haiku-4-5"
+		if r.IsSubagent != sub {
+			t.Errorf("%s: expected IsSubagent=%v", r.Model, sub)
+		}
+		if sub && r.AgentID != "agent-a123" || !sub && r.AgentID != "" {
+			t.Errorf("%s: unexpected agent ID %q", r.Model, r.AgentID)
+		}
+		if r.Session != "session-abc" {
+			t.Errorf("%s: expected session-abc, got %q", r.Model, r.Session)
+		}
+	}
+
 	// Only main session file should produce a session (not subagent).
 	if len(sessions) != 1 {
 		t.Fatalf("expected 1 session (main only), got %d", len(sessions))
@@ -184,7 +199,7 @@ func TestModelNormalization(t *testing.T) {
 	data := `{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-sonnet-4-5-20250929","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
 `
 	dir := setupTestDir(t, data)
-	records, _, warnings, err := parseDir(dir, Options{})
+	records, _, warnings, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -207,7 +222,7 @@ func TestSessionDuration(t *testing.T) {
 {"type":"user","timestamp":"2026-02-14T10:02:00.000Z","cwd":"/home/user/proj","message":{"role":"user","content":"thanks"}}
 `
 	dir := setupTestDir(t, data)
-	_, sessions, _, err := parseDir(dir, Options{})
+	_, sessions, _, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -231,7 +246,7 @@ func TestSessionFilteredByDate(t *testing.T) {
 `
 	dir := setupTestDir(t, data)
 	since, _ := time.Parse("2006-01-02", "2026-02-12")
-	_, sessions, _, err := parseDir(dir, Options{Since: since})
+	_, sessions, _, err := parseDir(dir, &Options{Since: since})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -264,7 +279,7 @@ func TestDisambiguateProjects(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	records, _, _, err := parseDir(dir, Options{})
+	records, _, _, err := parseDir(dir, &Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -313,7 +328,7 @@ func TestFilterDisambiguatedProject(t *testing.T) {
 	}
 
 	// Filter "toogly" should match only toogly/backend.
-	records, _, _, err := parseDir(dir, Options{Project: "toogly"})
+	records, _, _, err := parseDir(dir, &Options{Project: Filter{Include: []string{"toogly"}}})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -325,7 +340,7 @@ func TestFilterDisambiguatedProject(t *testing.T) {
 	}
 
 	// Filter "backend" should match both.
-	records, _, _, err = parseDir(dir, Options{Project: "backend"})
+	records, _, _, err = parseDir(dir, &Options{Project: Filter{Include: []string{"backend"}}})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -376,3 +391,458 @@ func TestDisambiguateProjectsUnit(t *testing.T) {
 		t.Errorf("expected 'my_game/backend', got %q", result["/home/user/my_game/backend"])
 	}
 }
+
+func TestLocationBucketing(t *testing.T) {
+	// 23:30 UTC is already the next day in UTC+9.
+	data := `{"type":"user","timestamp":"2026-02-14T23:00:00.000Z","cwd":"/home/user/proj","message":{"role":"user","content":"hello"}}
+{"type":"assistant","timestamp":"2026-02-14T23:30:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`
+	dir := setupTestDir(t, data)
+
+	records, sessions, _, err := parseDir(dir, &Options{Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[0].Time.Format("2006-01-02"); got != "2026-02-14" {
+		t.Errorf("UTC: expected record date 2026-02-14, got %s", got)
+	}
+	if len(sessions) != 1 || sessions[0].Date != "2026-02-14" {
+		t.Errorf("UTC: expected one session on 2026-02-14, got %+v", sessions)
+	}
+
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	records, sessions, _, err = parseDir(dir, &Options{Location: tokyo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := records[0].Time.Format("2006-01-02"); got != "2026-02-15" {
+		t.Errorf("UTC+9: expected record date 2026-02-15, got %s", got)
+	}
+	if len(sessions) != 1 || sessions[0].Date != "2026-02-15" {
+		t.Errorf("UTC+9: expected one session on 2026-02-15, got %+v", sessions)
+	}
+}
+
+func TestAliasMergesProjects(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"proj-main/s1.jsonl": `{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/src/shop","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`,
+		"proj-wt/s2.jsonl": `{"type":"assistant","timestamp":"2026-02-14T11:00:00.000Z","cwd":"/home/user/src/shop-feature-x","message":{"id":"msg_002","model":"claude-opus-4-6","usage":{"input_tokens":200,"output_tokens":100,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`,
+		"proj-sub/s3.jsonl": `{"type":"assistant","timestamp":"2026-02-14T12:00:00.000Z","cwd":"/home/user/src/shop/services/api","message":{"id":"msg_003","model":"claude-opus-4-6","usage":{"input_tokens":300,"output_tokens":150,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`,
+		"proj-other/s4.jsonl": `{"type":"assistant","timestamp":"2026-02-14T13:00:00.000Z","cwd":"/home/user/src/blog","message":{"id":"msg_004","model":"claude-opus-4-6","usage":{"input_tokens":400,"output_tokens":200,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	alias, err := ParseAlias("/home/user/src/shop*=shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, _, _, err := parseDir(dir, &Options{Aliases: []Alias{alias}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, got %d", len(records))
+	}
+	for _, r := range records {
+		want := "shop"
+		if r.Input == 400 {
+			want = "blog"
+		}
+		if r.Project != want {
+			t.Errorf("record with input %d: expected project %q, got %q", r.Input, want, r.Project)
+		}
+	}
+}
+
+func TestParseAlias(t *testing.T) {
+	tests := []struct {
+		rule  string
+		cwd   string
+		match bool
+	}{
+		{"/work/app=app", "/work/app", true},
+		{"/work/app=app", "/work/app/web", true},
+		{"/work/app=app", "/work/application", false},
+		{"/work/app-*=app", "/work/app-wt1/src", true},
+		{"re:/(api|web)-v[0-9]+$=svc", "/work/api-v2", true},
+		{"re:/(api|web)-v[0-9]+$=svc", "/work/api-v2/cmd", false},
+	}
+	for _, tt := range tests {
+		a, err := ParseAlias(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseAlias(%q): %v", tt.rule, err)
+		}
+		if got := a.Match(tt.cwd); got != tt.match {
+			t.Errorf("ParseAlias(%q).Match(%q) = %v, want %v", tt.rule, tt.cwd, got, tt.match)
+		}
+	}
+
+	for _, bad := range []string{"noequals", "=name", "/path=", "re:(=x", "/work/[=x"} {
+		if _, err := ParseAlias(bad); err == nil {
+			t.Errorf("ParseAlias(%q): expected error", bad)
+		}
+	}
+}
+
+func TestFilterBranch(t *testing.T) {
+	data := `{"type":"user","timestamp":"2026-02-14T09:00:00.000Z","cwd":"/home/user/proj","gitBranch":"main"}
+{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","gitBranch":"main","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"assistant","timestamp":"2026-02-14T11:00:00.000Z","cwd":"/home/user/proj","gitBranch":"feature/PAY-42-refunds","message":{"id":"msg_002","model":"claude-opus-4-6","usage":{"input_tokens":200,"output_tokens":100,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`
+	dir := setupTestDir(t, data)
+
+	records, sessions, _, err := parseDir(dir, &Options{Branch: "pay-42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record on branch matching 'pay-42', got %d", len(records))
+	}
+	if records[0].Branch != "feature/PAY-42-refunds" {
+		t.Errorf("expected branch 'feature/PAY-42-refunds', got %q", records[0].Branch)
+	}
+	// The time on the feature branch is its own session, so it is kept.
+	if len(sessions) != 1 {
+		t.Errorf("expected 1 session, got %d", len(sessions))
+	}
+
+	records, _, _, err = parseDir(dir, &Options{Branch: "release"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Errorf("expected 0 records for 'release', got %d", len(records))
+	}
+}
+
+func TestSessionTimePerBranch(t *testing.T) {
+	// A day on feat-login, then a late switch to main; the entry without a
+	// branch counts towards the one before it.
+	data := `{"type":"user","timestamp":"2026-02-14T09:00:00.000Z","cwd":"/home/user/proj","gitBranch":"feat-login"}
+{"type":"user","timestamp":"2026-02-14T09:05:00.000Z","cwd":"/home/user/proj"}
+{"type":"assistant","timestamp":"2026-02-14T23:00:00.000Z","cwd":"/home/user/proj","gitBranch":"feat-login","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"user","timestamp":"2026-02-14T23:30:00.000Z","cwd":"/home/user/proj","gitBranch":"main"}
+{"type":"user","timestamp":"2026-02-14T23:40:00.000Z","cwd":"/home/user/proj","gitBranch":"main"}
+`
+	dir := setupTestDir(t, data)
+	opts := &Options{Location: time.UTC, Idle: time.Hour}
+
+	_, sessions, _, err := parseDir(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Session{}
+	for _, s := range sessions {
+		got[s.Branch] = s
+	}
+	if s := got["feat-login"]; len(got) != 2 || s.Duration != 14*time.Hour+30*time.Minute || s.Active != 35*time.Minute {
+		t.Errorf("expected 14h30m (35m active) on feat-login, got %+v", sessions)
+	}
+	if s := got["main"]; s.Duration != 10*time.Minute || s.Active != 10*time.Minute {
+		t.Errorf("expected 10m on main, got %+v", s)
+	}
+
+	opts.Branch = "feat"
+	if _, sessions, _, err = parseDir(dir, opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Duration != 14*time.Hour+30*time.Minute {
+		t.Errorf("expected --branch feat to keep the feat-login time, got %+v", sessions)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	f := Filter{
+		Include: []string{"API", "web"},
+		Exclude: []string{"scratch"},
+		Regex:   []*regexp.Regexp{regexp.MustCompile(`^svc-\d+$`)},
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"billing-api", true},
+		{"Web", true},
+		{"svc-12", true},
+		{"svc-x", false},
+		{"api-scratch", false},
+		{"docs", false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.name); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	exclOnly := Filter{Exclude: []string{"tmp"}}
+	if !exclOnly.Match("shop") || exclOnly.Match("tmp-1") {
+		t.Error("exclude-only filter should pass everything except excluded names")
+	}
+	var zero Filter
+	if !zero.Match("") {
+		t.Error("zero filter should pass everything")
+	}
+}
+
+func TestFilterModel(t *testing.T) {
+	data := `{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"assistant","timestamp":"2026-02-14T10:01:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_002","model":"claude-haiku-4-5-20251001","usage":{"input_tokens":200,"output_tokens":100,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`
+	dir := setupTestDir(t, data)
+
+	records, _, _, err := parseDir(dir, &Options{Model: Filter{Include: []string{"haiku"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Model != "claude-haiku-4-5" {
+		t.Errorf("expected only the haiku record, got %+v", records)
+	}
+
+	records, _, _, err = parseDir(dir, &Options{Model: Filter{Exclude: []string{"haiku"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Model != "claude-opus-4-6" {
+		t.Errorf("expected only the opus record, got %+v", records)
+	}
+}
+
+func TestFilterModelSessions(t *testing.T) {
+	// The session in "a" used opus and haiku on the 14th and only haiku on
+	// the 15th; the session in "b" used only haiku.
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "proj", "a.jsonl"), `{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"assistant","timestamp":"2026-02-14T10:05:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_002","model":"claude-haiku-4-5-20251001","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"assistant","timestamp":"2026-02-15T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_003","model":"claude-haiku-4-5-20251001","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`)
+	writeFile(t, filepath.Join(dir, "proj", "b.jsonl"), `{"type":"assistant","timestamp":"2026-02-14T12:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_004","model":"claude-haiku-4-5-20251001","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`)
+
+	_, sessions, _, err := parseDir(dir, &Options{Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("expected 3 session days without a filter, got %+v", sessions)
+	}
+
+	_, sessions, _, err = parseDir(dir, &Options{Location: time.UTC, Model: Filter{Exclude: []string{"haiku"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != "a" || sessions[0].Date != "2026-02-14" {
+		t.Errorf("expected only session a on the 14th, got %+v", sessions)
+	}
+
+	records := []Record{{Session: "a", Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6"}}
+	merged := []Session{{ID: "a", Date: "2026-02-14"}, {ID: "b", Date: "2026-02-14"}}
+	if _, got := Select(records, merged, &Options{Location: time.UTC, Model: Filter{Include: []string{"opus"}}}); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("Select: expected only session a, got %+v", got)
+	}
+}
+
+func TestToolUses(t *testing.T) {
+	// msg_001 is streamed as two entries, one per content block; the second
+	// repeats the first tool call. A user entry with string content must not
+	// break parsing.
+	data := `{"type":"user","timestamp":"2026-02-14T09:59:00.000Z","cwd":"/home/user/proj","message":{"content":"fix the build"}}
+{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","content":[{"type":"text","text":"ok"},{"type":"tool_use","id":"tu_1","name":"Bash","input":{}}],"usage":{"input_tokens":100,"output_tokens":10,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"assistant","timestamp":"2026-02-14T10:00:01.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","content":[{"type":"tool_use","id":"tu_1","name":"Bash","input":{}},{"type":"tool_use","id":"tu_2","name":"Read","input":{}}],"usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"assistant","timestamp":"2026-02-14T10:01:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_002","model":"claude-opus-4-6","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":100,"output_tokens":5,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`
+	dir := setupTestDir(t, data)
+	records, sessions, _, err := parseDir(dir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if got := strings.Join(records[0].Tools, ","); got != "Bash,Read" {
+		t.Errorf("expected tools Bash,Read for msg_001, got %q", got)
+	}
+	if records[0].Output != 50 {
+		t.Errorf("expected the entry with max output tokens to be kept, got %d", records[0].Output)
+	}
+	if len(records[1].Tools) != 0 {
+		t.Errorf("expected no tools for a text-only response, got %v", records[1].Tools)
+	}
+	if len(sessions) != 1 || sessions[0].Duration != 2*time.Minute {
+		t.Errorf("expected the user entry to count towards session time, got %+v", sessions)
+	}
+}
+
+func TestServerToolUse(t *testing.T) {
+	data := `{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"server_tool_use":{"web_search_requests":2,"web_fetch_requests":1}}}}
+`
+	dir := setupTestDir(t, data)
+	records, _, _, err := parseDir(dir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	if records[0].WebSearches != 2 || records[0].WebFetches != 1 {
+		t.Errorf("expected 2 searches and 1 fetch, got %d and %d", records[0].WebSearches, records[0].WebFetches)
+	}
+}
+
+func TestSessionActiveTime(t *testing.T) {
+	// 10:00 → 10:10 → 10:15 active (15m), 45m lunch gap, 11:00 → 11:05 (5m).
+	data := `{"type":"user","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/home/user/proj"}
+{"type":"assistant","timestamp":"2026-02-14T10:10:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_001","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+{"type":"user","timestamp":"2026-02-14T11:00:00.000Z","cwd":"/home/user/proj"}
+{"type":"user","timestamp":"2026-02-14T10:15:00.000Z","cwd":"/home/user/proj"}
+{"type":"assistant","timestamp":"2026-02-14T11:05:00.000Z","cwd":"/home/user/proj","message":{"id":"msg_002","model":"claude-opus-4-6","usage":{"input_tokens":100,"output_tokens":50,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}}
+`
+	dir := setupTestDir(t, data)
+
+	tests := []struct {
+		idle   time.Duration
+		active time.Duration
+	}{
+		{0, 20 * time.Minute}, // DefaultIdle
+		{5 * time.Minute, 10 * time.Minute},
+		{time.Hour, 65 * time.Minute},
+	}
+	for _, tt := range tests {
+		_, sessions, _, err := parseDir(dir, &Options{Location: time.UTC, Idle: tt.idle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sessions) != 1 {
+			t.Fatalf("expected 1 session, got %d", len(sessions))
+		}
+		if sessions[0].Duration != 65*time.Minute {
+			t.Errorf("idle %v: expected wall-clock 1h05m, got %v", tt.idle, sessions[0].Duration)
+		}
+		if sessions[0].Active != tt.active {
+			t.Errorf("idle %v: expected active %v, got %v", tt.idle, tt.active, sessions[0].Active)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	alias, err := ParseAlias("/src/shop*=shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []Record{
+		{ID: "b", Time: time.Date(2026, 2, 2, 23, 30, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "shop-v2", CWD: "/src/shop-v2", Branch: "main"},
+		{ID: "a", Time: time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "blog", CWD: "/src/blog", Branch: "feat"},
+		{ID: "c", Time: time.Date(2026, 1, 20, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "blog", CWD: "/src/blog"},
+	}
+	sessions := []Session{
+		{ID: "s1", Date: "2026-02-03", Project: "shop-v2", CWD: "/src/shop-v2", Branch: "main"},
+		{ID: "s2", Date: "2026-01-20", Project: "blog", CWD: "/src/blog"},
+	}
+	opts := &Options{
+		Since:    time.Date(2026, 2, 1, 0, 0, 0, 0, berlin),
+		Location: berlin,
+		Aliases:  []Alias{alias},
+	}
+
+	gotRecords, gotSessions := Select(records, sessions, opts)
+	if len(gotRecords) != 2 || gotRecords[0].ID != "a" || gotRecords[1].ID != "b" {
+		t.Fatalf("expected records a, b in time order, got %+v", gotRecords)
+	}
+	if r := gotRecords[1]; r.Project != "shop" || r.Time.Location() != berlin || r.Time.Day() != 3 {
+		t.Errorf("expected aliased project and Berlin time, got %q at %v", r.Project, r.Time)
+	}
+	if len(gotSessions) != 1 || gotSessions[0].Project != "shop" {
+		t.Errorf("expected the aliased February session only, got %+v", gotSessions)
+	}
+
+	opts.Model = Filter{Include: []string{"opus"}}
+	opts.Branch = "MAIN"
+	if gotRecords, _ = Select(records, sessions, opts); len(gotRecords) != 1 || gotRecords[0].ID != "b" {
+		t.Errorf("expected only record b with model and branch filters, got %+v", gotRecords)
+	}
+
+	// Without a location, records keep the zone they were exported in, the
+	// one their sessions were dated in: b is still on February 2 in UTC.
+	opts = &Options{Until: time.Date(2026, 2, 2, 23, 59, 59, 0, time.Local)}
+	gotRecords, gotSessions = Select(records, sessions, opts)
+	if len(gotRecords) != 3 || gotRecords[2].ID != "b" || gotRecords[2].Time.Location() != time.UTC {
+		t.Errorf("expected all records in UTC, got %+v", gotRecords)
+	}
+	if len(gotSessions) != 1 || gotSessions[0].ID != "s2" {
+		t.Errorf("expected only session s2 before February 3, got %+v", gotSessions)
+	}
+
+	opts.Model = Filter{Regex: []*regexp.Regexp{regexp.MustCompile("^claude-opus")}}
+	if gotRecords, _ = Select(records, sessions, opts); len(gotRecords) != 2 || gotRecords[0].ID != "c" {
+		t.Errorf("expected opus records c, b with a model regex, got %+v", gotRecords)
+	}
+}
+
+func TestStreamDir(t *testing.T) {
+	dir := t.TempDir()
+	// Both projects are named "app": telling them apart takes every file's
+	// CWD before the first file is written out.
+	writeFile(t, filepath.Join(dir, "a", "s1.jsonl"), `{"type":"user","timestamp":"2026-02-14T09:00:00.000Z","cwd":"/work/app"}
+{"type":"assistant","timestamp":"2026-02-14T10:02:00.000Z","cwd":"/work/app","message":{"id":"m2","model":"claude-opus-4-6","usage":{"input_tokens":1}}}
+{"type":"assistant","timestamp":"2026-02-14T10:00:00.000Z","cwd":"/work/app","message":{"id":"m1","model":"claude-opus-4-6","usage":{"input_tokens":1}}}
+`)
+	writeFile(t, filepath.Join(dir, "b", "s2.jsonl"), `{"type":"assistant","timestamp":"2026-02-14T11:00:00.000Z","cwd":"/home/app","message":{"id":"m3","model":"claude-opus-4-6","usage":{"input_tokens":1}}}
+`)
+	want, _, _, err := parseDir(dir, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files [][]Record
+	if _, err = streamDir(dir, &Options{}, func(records []Record) error {
+		files = append(files, records)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected one call per file, got %d", len(files))
+	}
+	var got []Record
+	for _, records := range files {
+		if !slices.IsSortedFunc(records, func(a, b Record) int { return a.Time.Compare(b.Time) }) {
+			t.Errorf("expected a file's records in time order, got %+v", records)
+		}
+		got = append(got, records...)
+	}
+	slices.SortFunc(got, func(a, b Record) int { return a.Time.Compare(b.Time) })
+	if len(got) != 3 || len(want) != 3 || want[0].Project == want[2].Project {
+		t.Fatalf("expected 3 records in two distinct projects, streamed %+v, parsed %+v", got, want)
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Project != want[i].Project {
+			t.Errorf("record %d: streamed %s in %q, parsed %s in %q", i, got[i].ID, got[i].Project, want[i].ID, want[i].Project)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	if _, err = streamDir(dir, &Options{}, func([]Record) error {
+		calls++
+		return stop
+	}); !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected streaming to stop at the first error, got %v after %d calls", err, calls)
+	}
+}
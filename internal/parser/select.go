@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Select applies opts to records and sessions that were not read from the
+// local logs, such as merged bundles: the date range, aliases (matched
+// against CWD), and the project, model and branch filters. Session dates
+// are kept as recorded, so record times are converted to opts.Location only
+// when it is set, and otherwise keep the offset they were exported with;
+// the caller must check that sessions were dated in opts.Location.
+func Select(records []Record, sessions []Session, opts *Options) ([]Record, []Session) {
+	branch := strings.ToLower(opts.Branch)
+	keep := func(project *string, br, cwd string) bool {
+		if cwd != "" {
+			if name, ok := matchAlias(opts.Aliases, cwd); ok {
+				*project = name
+			}
+		}
+		return opts.Project.Match(*project) && strings.Contains(strings.ToLower(br), branch)
+	}
+
+	var since, until string
+	if !opts.Since.IsZero() {
+		since = opts.Since.In(opts.location()).Format("2006-01-02")
+	}
+	if !opts.Until.IsZero() {
+		until = opts.Until.In(opts.location()).Format("2006-01-02")
+	}
+
+	var outRecords []Record
+	for i := range records {
+		r := records[i]
+		if opts.Location != nil {
+			r.Time = r.Time.In(opts.Location)
+		}
+		if date := r.Time.Format("2006-01-02"); since != "" && date < since || until != "" && date > until {
+			continue
+		}
+		if keep(&r.Project, r.Branch, r.CWD) && opts.Model.Match(r.Model) {
+			outRecords = append(outRecords, r)
+		}
+	}
+
+	var outSessions []Session
+	for i := range sessions {
+		s := sessions[i]
+		if since != "" && s.Date < since || until != "" && s.Date > until {
+			continue
+		}
+		if keep(&s.Project, s.Branch, s.CWD) {
+			outSessions = append(outSessions, s)
+		}
+	}
+	if opts.Model.set() {
+		outSessions = sessionsWithRecords(outSessions, outRecords)
+	}
+
+	slices.SortFunc(outRecords, func(a, b Record) int {
+		return a.Time.Compare(b.Time)
+	})
+	slices.SortFunc(outSessions, func(a, b Session) int {
+		return cmp.Compare(a.Date, b.Date)
+	})
+	return outRecords, outSessions
+}
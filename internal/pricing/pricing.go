@@ -1,6 +1,10 @@
 package pricing
 
-import "regexp"
+import (
+	"maps"
+	"regexp"
+	"slices"
+)
 
 // Price per 1M tokens for each token type.
 type ModelPricing struct {
@@ -27,6 +31,30 @@ var models = map[string]ModelPricing{
 	"claude-haiku-3":    {Input: 0.25, Output: 1.25, CacheWrite: 0.50, CacheRead: 0.03},
 }
 
+// Server-side tool fees in USD per request, billed on top of tokens.
+// Web fetch has no per-request fee; fetched content is billed as tokens.
+const (
+	WebSearchPrice = 10.0 / 1000
+	WebFetchPrice  = 0.0
+)
+
+// Monthly subscription fees in USD per seat.
+// Source: https://claude.com/pricing
+var plans = map[string]float64{
+	"pro":   20,
+	"max5":  100,
+	"max20": 200,
+}
+
+// PlanNames lists the built-in subscription plans.
+var PlanNames = []string{"pro", "max5", "max20"}
+
+// PlanFee returns the monthly per-seat fee of a built-in plan.
+func PlanFee(name string) (float64, bool) {
+	fee, ok := plans[name]
+	return fee, ok
+}
+
 var dateSuffix = regexp.MustCompile(`-\d{8}$`)
 
 // NormalizeModel strips date suffixes like -20250929 from model names.
@@ -40,6 +68,11 @@ func Lookup(model string) (ModelPricing, bool) {
 	return p, ok
 }
 
+// Models returns the names of all priced models, sorted.
+func Models() []string {
+	return slices.Sorted(maps.Keys(models))
+}
+
 // Cost calculates the total cost in USD for the given token counts.
 // Returns -1 if the model is unknown.
 func Cost(model string, input, output, cacheWrite, cacheRead int) float64 {
@@ -52,3 +85,22 @@ func Cost(model string, input, output, cacheWrite, cacheRead int) float64 {
 		float64(cacheWrite)*p.CacheWrite +
 		float64(cacheRead)*p.CacheRead) / 1_000_000
 }
+
+// ServerToolCost calculates the per-request fees in USD for server-side tool
+// use. It does not depend on the model.
+func ServerToolCost(webSearches, webFetches int) float64 {
+	return float64(webSearches)*WebSearchPrice + float64(webFetches)*WebFetchPrice
+}
+
+// CacheSavings returns, in USD, what cache reads saved compared with paying
+// the full input price, and the premium paid for cache writes over the input
+// price. ok is false if the model is unknown.
+func CacheSavings(model string, cacheWrite, cacheRead int) (saved, premium float64, ok bool) {
+	p, ok := models[NormalizeModel(model)]
+	if !ok {
+		return 0, 0, false
+	}
+	saved = float64(cacheRead) * (p.Input - p.CacheRead) / 1_000_000
+	premium = float64(cacheWrite) * (p.CacheWrite - p.Input) / 1_000_000
+	return saved, premium, true
+}
@@ -2,6 +2,7 @@ package pricing
 
 import (
 	"math"
+	"slices"
 	"testing"
 )
 
@@ -75,3 +76,58 @@ func TestNormalizeModel(t *testing.T) {
 		}
 	}
 }
+
+func TestCacheSavings(t *testing.T) {
+	// Reads: 1M * ($5 - $0.50)/1M = $4.50 saved.
+	// Writes: 100K * ($10 - $5)/1M = $0.50 premium.
+	saved, premium, ok := CacheSavings("claude-opus-4-6", 100_000, 1_000_000)
+	if !ok {
+		t.Fatal("expected known model")
+	}
+	if !almostEqual(saved, 4.5) {
+		t.Errorf("expected saved 4.5, got %f", saved)
+	}
+	if !almostEqual(premium, 0.5) {
+		t.Errorf("expected premium 0.5, got %f", premium)
+	}
+
+	if _, _, ok := CacheSavings("unknown-model", 1, 1); ok {
+		t.Error("expected unknown model to report ok=false")
+	}
+}
+
+func TestPlanFee(t *testing.T) {
+	for _, name := range PlanNames {
+		if fee, ok := PlanFee(name); !ok || fee <= 0 {
+			t.Errorf("expected a fee for plan %q, got %v, %v", name, fee, ok)
+		}
+	}
+	if fee, _ := PlanFee("max5"); fee != 100 {
+		t.Errorf("expected max5 fee 100, got %v", fee)
+	}
+	if _, ok := PlanFee("enterprise"); ok {
+		t.Error("expected unknown plan to report ok=false")
+	}
+}
+
+func TestServerToolCost(t *testing.T) {
+	// 3 searches at $10/1K; fetches are free.
+	if got := ServerToolCost(3, 5); !almostEqual(got, 0.03) {
+		t.Errorf("expected 0.03, got %f", got)
+	}
+	if got := ServerToolCost(0, 0); got != 0 {
+		t.Errorf("expected 0, got %f", got)
+	}
+}
+
+func TestModels(t *testing.T) {
+	names := Models()
+	if !slices.IsSorted(names) || !slices.Contains(names, "claude-opus-4-6") {
+		t.Errorf("expected sorted model names including claude-opus-4-6, got %v", names)
+	}
+	for _, n := range names {
+		if _, ok := Lookup(n); !ok {
+			t.Errorf("listed model %q has no pricing", n)
+		}
+	}
+}
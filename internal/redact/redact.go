@@ -0,0 +1,134 @@
+// Package redact replaces identifying names in parsed records with
+// deterministic pseudonyms, so reports and bundles can be shared without
+// revealing client or project names.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// Mode selects how names are replaced.
+type Mode string
+
+const (
+	Hash  Mode = "hash"  // short hex digest, e.g. "3fa9c1d2e0"
+	Alias Mode = "alias" // readable word pair and short digest, e.g. "amber-falcon-3fa9"
+)
+
+// ParseMode validates a redaction mode name.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(s)); m {
+	case Hash, Alias:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown mode %q (want hash or alias)", s)
+	}
+}
+
+// Redactor derives pseudonyms from an HMAC of each name keyed with a salt.
+// The same salt gives the same pseudonyms on every machine and run, so
+// redacted snapshots can still be merged; without it, common names can be
+// recovered by guessing.
+type Redactor struct {
+	mode     Mode
+	salt     []byte
+	sessions bool
+}
+
+// New returns a Redactor for mode and salt. Session IDs are dropped unless
+// sessions is set, in which case they become opaque tokens.
+func New(mode Mode, salt string, sessions bool) *Redactor {
+	return &Redactor{mode: mode, salt: []byte(salt), sessions: sessions}
+}
+
+func (r *Redactor) sum(s string) []byte {
+	h := hmac.New(sha256.New, r.salt)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// name returns the pseudonym of a project, repository or branch name. It
+// depends only on the salt and the name, so a name gets the same pseudonym
+// in every export; the digest suffix keeps different names from sharing an
+// alias, which only 4096 word pairs could not.
+func (r *Redactor) name(s string) string {
+	if s == "" {
+		return ""
+	}
+	sum := r.sum(s)
+	if r.mode == Alias {
+		return adjectives[int(sum[0])%len(adjectives)] + "-" + nouns[int(sum[1])%len(nouns)] + "-" + hex.EncodeToString(sum[2:4])
+	}
+	return hex.EncodeToString(sum[:5])
+}
+
+// Apply redacts records and sessions in place. Project, repository and
+// branch names become pseudonyms, working directories become
+// "/redacted/<pseudonym>", and session IDs are dropped, or become opaque
+// tokens that still group a session's records if the Redactor keeps
+// sessions. Tokens are the same in every export with the same salt, so
+// they link a session across them. Empty values stay empty.
+func (r *Redactor) Apply(records []parser.Record, sessions []parser.Session) {
+	cache := map[string]string{}
+	pseudo := func(s string) string {
+		p, ok := cache[s]
+		if !ok {
+			p = r.name(s)
+			cache[s] = p
+		}
+		return p
+	}
+	path := func(p string) string {
+		if p == "" {
+			return ""
+		}
+		return "/redacted/" + pseudo(p)
+	}
+	id := func(s string) string {
+		if s == "" || !r.sessions {
+			return ""
+		}
+		return hex.EncodeToString(r.sum("session:" + s)[:8])
+	}
+
+	for i := range records {
+		rec := &records[i]
+		rec.Project, rec.Repo, rec.Branch = pseudo(rec.Project), pseudo(rec.Repo), pseudo(rec.Branch)
+		rec.CWD = path(rec.CWD)
+		rec.Session = id(rec.Session)
+	}
+	for i := range sessions {
+		s := &sessions[i]
+		s.Project, s.Repo, s.Branch = pseudo(s.Project), pseudo(s.Repo), pseudo(s.Branch)
+		s.CWD = path(s.CWD)
+		s.ID = id(s.ID)
+	}
+}
+
+var adjectives = []string{
+	"amber", "ancient", "autumn", "bold", "brave", "bright", "calm", "clever",
+	"cosmic", "crimson", "crisp", "dusty", "eager", "early", "electric", "emerald",
+	"fancy", "fierce", "gentle", "golden", "grand", "happy", "hidden", "humble",
+	"icy", "jolly", "keen", "lively", "lucky", "lunar", "mellow", "misty",
+	"modest", "noble", "olive", "patient", "polar", "proud", "quiet", "rapid",
+	"rustic", "scarlet", "shy", "silent", "silver", "sleek", "solar", "spicy",
+	"stormy", "sunny", "swift", "tidy", "tiny", "topaz", "velvet", "vivid",
+	"wandering", "warm", "wild", "windy", "wise", "witty", "young", "zesty",
+}
+
+var nouns = []string{
+	"badger", "bear", "beaver", "bison", "cobra", "condor", "crane", "dingo",
+	"dolphin", "eagle", "falcon", "ferret", "finch", "fox", "gazelle", "gecko",
+	"heron", "ibis", "jackal", "jaguar", "koala", "lemur", "leopard", "lynx",
+	"magpie", "marmot", "marten", "moose", "narwhal", "ocelot", "orca", "otter",
+	"owl", "panda", "panther", "pelican", "penguin", "puffin", "quail", "rabbit",
+	"raven", "salmon", "seal", "shrike", "sparrow", "squid", "stork", "swan",
+	"tapir", "tiger", "toucan", "trout", "turtle", "viper", "walrus", "weasel",
+	"whale", "wolf", "wombat", "wren", "yak", "zebra", "ibex", "hare",
+}
@@ -0,0 +1,111 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+func sample() ([]parser.Record, []parser.Session) {
+	records := []parser.Record{
+		{Project: "acme-portal", Repo: "acme-portal", CWD: "/home/u/clients/acme-portal", Branch: "ACME-12", Session: "3f2a"},
+		{Project: "blog", Repo: "blog", CWD: "/home/u/blog", Session: "9c1d"},
+	}
+	sessions := []parser.Session{{ID: "3f2a", Project: "acme-portal", Repo: "acme-portal", CWD: "/home/u/clients/acme-portal", Branch: "ACME-12"}}
+	return records, sessions
+}
+
+func TestApplyHash(t *testing.T) {
+	records, sessions := sample()
+	New(Hash, "salt", true).Apply(records, sessions)
+
+	r := records[0]
+	if !regexp.MustCompile(`^[0-9a-f]{10}$`).MatchString(r.Project) {
+		t.Errorf("expected a hex pseudonym, got %q", r.Project)
+	}
+	if r.Repo != r.Project || sessions[0].Project != r.Project {
+		t.Errorf("expected the same name to get the same pseudonym everywhere: %+v, %+v", r, sessions[0])
+	}
+	if r.Session == "3f2a" || r.Session != sessions[0].ID || r.Session == records[1].Session {
+		t.Errorf("expected opaque session IDs that still match: %q, %q, %q", r.Session, sessions[0].ID, records[1].Session)
+	}
+	if !strings.HasPrefix(r.CWD, "/redacted/") || r.CWD != sessions[0].CWD || regexp.MustCompile(`(?i)acme|home`).MatchString(r.CWD+r.Branch) {
+		t.Errorf("expected redacted path and branch, got %q and %q", r.CWD, r.Branch)
+	}
+	if records[1].Branch != "" {
+		t.Errorf("expected an empty branch to stay empty, got %q", records[1].Branch)
+	}
+
+	again, _ := sample()
+	New(Hash, "salt", true).Apply(again, nil)
+	if again[0].Project != r.Project || again[0].Session != r.Session {
+		t.Error("expected the same salt to give the same pseudonyms")
+	}
+	other, _ := sample()
+	New(Hash, "pepper", true).Apply(other, nil)
+	if other[0].Project == r.Project {
+		t.Error("expected a different salt to give different pseudonyms")
+	}
+}
+
+func TestApplyDropsSessions(t *testing.T) {
+	records, sessions := sample()
+	New(Hash, "salt", false).Apply(records, sessions)
+	if records[0].Session != "" || records[1].Session != "" || sessions[0].ID != "" {
+		t.Errorf("expected session IDs dropped, got %q, %q, %q", records[0].Session, records[1].Session, sessions[0].ID)
+	}
+}
+
+func TestApplyAlias(t *testing.T) {
+	records := make([]parser.Record, 300)
+	for i := range records {
+		records[i].Project = fmt.Sprintf("client-%d", i)
+	}
+	New(Alias, "salt", false).Apply(records, nil)
+
+	// 300 names over 4096 word pairs collide; digest suffixes keep them distinct.
+	alias := regexp.MustCompile(`^[a-z]+-[a-z]+-[0-9a-f]{4}$`)
+	seen := map[string]bool{}
+	for i := range records {
+		p := records[i].Project
+		if !alias.MatchString(p) || seen[p] {
+			t.Fatalf("expected unique word-pair aliases, got %q twice or malformed", p)
+		}
+		seen[p] = true
+	}
+}
+
+func TestAliasIndependentOfOtherNames(t *testing.T) {
+	// Two exports share "acme" and "blog"; each has names the other lacks.
+	first := make([]parser.Record, 0, 102)
+	second := make([]parser.Record, 0, 102)
+	for i := range 100 {
+		first = append(first, parser.Record{Project: fmt.Sprintf("client-%d", i)})
+		second = append(second, parser.Record{Project: fmt.Sprintf("vendor-%d", i)})
+	}
+	first = append(first, parser.Record{Project: "acme"}, parser.Record{Project: "blog"})
+	second = append([]parser.Record{{Project: "blog"}, {Project: "acme"}}, second...)
+
+	r := New(Alias, "salt", false)
+	r.Apply(first, nil)
+	r.Apply(second, nil)
+	if first[100].Project != second[1].Project || first[101].Project != second[0].Project {
+		t.Errorf("expected shared names to get the same alias: acme %q/%q, blog %q/%q",
+			first[100].Project, second[1].Project, first[101].Project, second[0].Project)
+	}
+	if first[100].Project == first[101].Project {
+		t.Errorf("expected different names to get different aliases, got %q", first[100].Project)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode("Alias"); err != nil || m != Alias {
+		t.Errorf("expected alias, got %q, %v", m, err)
+	}
+	if _, err := ParseMode("rot13"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,259 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// AnomalyKind is the unit checked for unusual spend.
+type AnomalyKind string
+
+const (
+	AnomalyDay     AnomalyKind = "day"
+	AnomalySession AnomalyKind = "session"
+	AnomalyRequest AnomalyKind = "request"
+)
+
+// AnomalyKinds lists every kind in the order they are checked.
+var AnomalyKinds = []AnomalyKind{AnomalyDay, AnomalySession, AnomalyRequest}
+
+// ParseAnomalyKind validates an anomaly kind name.
+func ParseAnomalyKind(s string) (AnomalyKind, error) {
+	k := AnomalyKind(strings.ToLower(s))
+	if !slices.Contains(AnomalyKinds, k) {
+		return "", fmt.Errorf("unknown kind %q (want day, session or request)", s)
+	}
+	return k, nil
+}
+
+// AnomalyOptions controls FindAnomalies.
+type AnomalyOptions struct {
+	Kinds   []AnomalyKind // empty means all
+	Factor  float64       // flag costs above Threshold(median, MAD, Factor)
+	Window  int           // days of history forming the baseline
+	MinCost float64       // ignore costs below this, in USD
+	From    string        // YYYY-MM-DD; earlier data only feeds baselines
+}
+
+// minHistory is the fewest baseline samples an anomaly is judged against.
+const minHistory = 3
+
+// madScale makes the median absolute deviation comparable to a standard
+// deviation for normally distributed data.
+const madScale = 1.4826
+
+// minSpread is the smallest spread, as a fraction of the median. Without it
+// a steady baseline (MAD near zero) would flag any small rise.
+const minSpread = 0.1
+
+// Threshold returns the cost above which a sample is flagged against a
+// baseline: median + factor × max(1.4826 × MAD, median / 10).
+func Threshold(med, dev, factor float64) float64 {
+	return med + factor*max(dev*madScale, med*minSpread)
+}
+
+// Anomaly is a day, session or request whose cost exceeded its project's
+// rolling baseline.
+type Anomaly struct {
+	Kind      AnomalyKind
+	Date      string // YYYY-MM-DD
+	Project   string
+	Key       string // date, session ID or request time (RFC 3339)
+	Detail    string // context: request count, model, tools
+	Cost      float64
+	Median    float64 // baseline median cost over the window
+	MAD       float64 // baseline median absolute deviation
+	Threshold float64 // cost above which it was flagged; see Threshold
+	Requests  int     // requests behind the cost
+}
+
+// Multiple returns the cost as a multiple of the baseline median.
+func (a *Anomaly) Multiple() float64 {
+	if a.Median == 0 {
+		return 0
+	}
+	return a.Cost / a.Median
+}
+
+// sample is one cost observation for a project on a date.
+type sample struct {
+	kind     AnomalyKind
+	date     string
+	project  string
+	key      string
+	detail   string
+	cost     float64
+	requests int
+}
+
+// FindAnomalies compares the cost of each day, session and request of a
+// project with the median and MAD of the same kind over the project's
+// previous opts.Window days. Costs are flagged when they exceed Threshold:
+// the median plus opts.Factor times the spread, where the spread is the
+// scaled MAD but at least a tenth of the median. Records with an unknown model contribute no
+// cost. Results are ordered by date, then by cost descending.
+func FindAnomalies(records []parser.Record, opts AnomalyOptions) []Anomaly {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = AnomalyKinds
+	}
+
+	var out []Anomaly //nolint:prealloc // the number of anomalies is unknown
+	for _, kind := range kinds {
+		samples := collectSamples(records, kind)
+		byProject := map[string][]*sample{}
+		for i := range samples {
+			s := &samples[i]
+			byProject[s.project] = append(byProject[s.project], s)
+		}
+		for _, ps := range byProject {
+			out = append(out, detect(ps, &opts)...)
+		}
+	}
+
+	slices.SortFunc(out, func(a, b Anomaly) int {
+		if c := cmp.Compare(a.Date, b.Date); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Cost, a.Cost)
+	})
+	return out
+}
+
+// collectSamples returns the costs of kind in records.
+func collectSamples(records []parser.Record, kind AnomalyKind) []sample {
+	type agg struct {
+		sample
+		models map[string]bool
+		agents map[string]bool
+	}
+	groups := map[string]*agg{}
+	var order []string
+	var out []sample
+
+	for i := range records {
+		r := &records[i]
+		cost := max(RecordCost(r), 0)
+		date := r.Time.Format("2006-01-02")
+
+		if kind == AnomalyRequest {
+			detail := strings.TrimPrefix(r.Model, "claude-")
+			if len(r.Tools) > 0 {
+				detail += " · " + strings.Join(slices.Compact(slices.Sorted(slices.Values(r.Tools))), ", ")
+			}
+			if r.IsSubagent {
+				detail += " · " + r.AgentID
+			}
+			out = append(out, sample{
+				kind: kind, date: date, project: r.Project,
+				key: r.Time.Format(time.RFC3339), detail: detail, cost: cost, requests: 1,
+			})
+			continue
+		}
+
+		key := date
+		if kind == AnomalySession {
+			if r.Session == "" {
+				continue // dropped by --redact: no session to judge
+			}
+			key = r.Session
+		}
+		id := r.Project + "\x00" + key
+		g, ok := groups[id]
+		if !ok {
+			g = &agg{
+				sample: sample{kind: kind, date: date, project: r.Project, key: key},
+				models: map[string]bool{},
+				agents: map[string]bool{},
+			}
+			groups[id] = g
+			order = append(order, id)
+		}
+		g.cost += cost
+		g.requests++
+		g.models[strings.TrimPrefix(r.Model, "claude-")] = true
+		if r.IsSubagent {
+			g.agents[r.AgentID] = true
+		}
+	}
+
+	for _, id := range order {
+		g := groups[id]
+		g.detail = fmt.Sprintf("%d requests · %s", g.requests, strings.Join(slices.Sorted(maps.Keys(g.models)), ", "))
+		if n := len(g.agents); n > 0 {
+			g.detail += fmt.Sprintf(" · %d subagents", n)
+		}
+		out = append(out, g.sample)
+	}
+	return out
+}
+
+// detect flags samples of one project and kind against their baselines.
+func detect(samples []*sample, opts *AnomalyOptions) []Anomaly {
+	slices.SortStableFunc(samples, func(a, b *sample) int { return cmp.Compare(a.date, b.date) })
+
+	var out []Anomaly
+	baselines := map[string][2]float64{} // date → median, MAD
+	for _, s := range samples {
+		if s.date < opts.From || s.cost < opts.MinCost {
+			continue
+		}
+		base, ok := baselines[s.date]
+		if !ok {
+			day, err := time.Parse("2006-01-02", s.date)
+			if err != nil {
+				continue
+			}
+			start := day.AddDate(0, 0, -opts.Window).Format("2006-01-02")
+			var history []float64
+			for _, h := range samples {
+				if h.date >= start && h.date < s.date {
+					history = append(history, h.cost)
+				}
+			}
+			base = [2]float64{math.NaN(), 0}
+			if len(history) >= minHistory {
+				m := median(history)
+				base = [2]float64{m, mad(history, m)}
+			}
+			baselines[s.date] = base
+		}
+		med, dev := base[0], base[1]
+		if math.IsNaN(med) {
+			continue
+		}
+		if limit := Threshold(med, dev, opts.Factor); s.cost > limit {
+			out = append(out, Anomaly{
+				Kind: s.kind, Date: s.date, Project: s.project, Key: s.key, Detail: s.detail,
+				Cost: s.cost, Median: med, MAD: dev, Threshold: limit, Requests: s.requests,
+			})
+		}
+	}
+	return out
+}
+
+// median returns the median of values, reordering them.
+func median(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// mad returns the median absolute deviation of values around m.
+func mad(values []float64, m float64) float64 {
+	dev := make([]float64, len(values))
+	for i, v := range values {
+		dev[i] = math.Abs(v - m)
+	}
+	return median(dev)
+}
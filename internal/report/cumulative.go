@@ -0,0 +1,46 @@
+package report
+
+import "slices"
+
+// CumulativePoint is the running total through one key of a date report.
+type CumulativePoint struct {
+	Date   string
+	Cost   float64 // -1 once a day with unknown cost has been included
+	Tokens int
+}
+
+// Cumulate computes running totals in ascending key order, which is
+// chronological for date reports. It fills Cumulative and sets CumCost and
+// CumTokens on the first row of each key (per-model rows of a date share
+// one running total) and on Total.
+func (r *Report) Cumulate() {
+	first := map[string]int{}
+	sums := map[string]*Row{}
+	var keys []string
+	for i := range r.Rows {
+		row := &r.Rows[i]
+		s, ok := sums[row.Key]
+		if !ok {
+			s = &Row{Key: row.Key}
+			sums[row.Key] = s
+			first[row.Key] = i
+			keys = append(keys, row.Key)
+		}
+		s.Add(row)
+	}
+	slices.Sort(keys)
+
+	r.Cumulative = make([]CumulativePoint, 0, len(keys))
+	var cost float64
+	var tokens int
+	for _, k := range keys {
+		s := sums[k]
+		cost = addKnown(cost, s.Cost)
+		tokens += s.Input + s.Output + s.CacheWrite + s.CacheRead
+		r.Cumulative = append(r.Cumulative, CumulativePoint{Date: k, Cost: cost, Tokens: tokens})
+
+		row := &r.Rows[first[k]]
+		row.CumCost, row.CumTokens = cost, tokens
+	}
+	r.Total.CumCost, r.Total.CumTokens = cost, tokens
+}
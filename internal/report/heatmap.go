@@ -0,0 +1,75 @@
+package report
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// Metric selects the value summed into heatmap cells.
+type Metric string
+
+const (
+	MetricCost   Metric = "cost"
+	MetricTokens Metric = "tokens"
+)
+
+// ParseMetric validates a metric name.
+func ParseMetric(s string) (Metric, error) {
+	switch m := Metric(s); m {
+	case MetricCost, MetricTokens:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown metric %q (want cost or tokens)", s)
+	}
+}
+
+// Weekdays lists heatmap rows in order, Monday first.
+var Weekdays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday,
+	time.Friday, time.Saturday, time.Sunday,
+}
+
+// Heatmap holds a metric summed by weekday (rows, Monday first) and hour of
+// day (columns) in the records' time zone.
+type Heatmap struct {
+	Metric Metric
+	Cells  [7][24]float64
+}
+
+// ByHour aggregates records into a weekday × hour heatmap. Records with an
+// unknown model contribute no cost.
+func ByHour(records []parser.Record, metric Metric) Heatmap {
+	h := Heatmap{Metric: metric}
+	for i := range records {
+		r := &records[i]
+		v := float64(r.Input + r.Output + r.CacheWrite + r.CacheRead)
+		if metric == MetricCost {
+			v = max(RecordCost(r), 0)
+		}
+		day := (int(r.Time.Weekday()) + 6) % 7 // Monday = 0
+		h.Cells[day][r.Time.Hour()] += v
+	}
+	return h
+}
+
+// Max returns the largest cell value.
+func (h *Heatmap) Max() float64 {
+	peak := 0.0
+	for day := range h.Cells {
+		for _, v := range h.Cells[day] {
+			peak = max(peak, v)
+		}
+	}
+	return peak
+}
+
+// RowTotal returns the sum of a weekday row.
+func (h *Heatmap) RowTotal(day int) float64 {
+	total := 0.0
+	for _, v := range h.Cells[day] {
+		total += v
+	}
+	return total
+}
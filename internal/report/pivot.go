@@ -0,0 +1,86 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// Pivot is a matrix of one metric with one dimension down the rows and
+// another across the columns. Unknown costs are -1 in Values and make the
+// affected totals -1.
+type Pivot struct {
+	Rows, Cols Dimension
+	Metric     Metric
+	RowKeys    []string
+	ColKeys    []string
+	Values     [][]float64 // [row][col]
+	RowTotals  []float64
+	ColTotals  []float64
+	Total      float64
+}
+
+// ParsePivot parses a "ROWS,COLS" dimension pair.
+func ParsePivot(s string) (rows, cols Dimension, err error) {
+	r, c, ok := strings.Cut(s, ",")
+	if !ok {
+		return "", "", fmt.Errorf("want ROWS,COLS, got %q", s)
+	}
+	if rows, err = ParseDimension(r); err != nil {
+		return "", "", err
+	}
+	if cols, err = ParseDimension(c); err != nil {
+		return "", "", err
+	}
+	if rows == cols {
+		return "", "", fmt.Errorf("rows and columns are both %s", rows)
+	}
+	return rows, cols, nil
+}
+
+// PivotBy builds a pivot from a two-level nested report of records.
+func PivotBy(rows, cols Dimension, metric Metric, records []parser.Record) Pivot {
+	rpt := Nested([]Dimension{rows, cols}, records, nil)
+	colKeys := By(cols, records, nil, false).Rows
+
+	p := Pivot{Rows: rows, Cols: cols, Metric: metric}
+	colIndex := map[string]int{}
+	for i := range colKeys {
+		colIndex[colKeys[i].Key] = i
+		p.ColKeys = append(p.ColKeys, colKeys[i].Key)
+	}
+	p.ColTotals = make([]float64, len(p.ColKeys))
+
+	value := func(r *Row) float64 {
+		if metric == MetricTokens {
+			return float64(r.Input + r.Output + r.CacheWrite + r.CacheRead)
+		}
+		return r.Cost
+	}
+
+	for i := range rpt.Rows {
+		row := &rpt.Rows[i]
+		p.RowKeys = append(p.RowKeys, row.Key)
+		p.RowTotals = append(p.RowTotals, value(row))
+		cells := make([]float64, len(p.ColKeys))
+		for j := range row.Children {
+			child := &row.Children[j]
+			c := colIndex[child.Key]
+			v := value(child)
+			cells[c] = v
+			p.ColTotals[c] = addKnown(p.ColTotals[c], v)
+		}
+		p.Values = append(p.Values, cells)
+	}
+	p.Total = value(&rpt.Total)
+	return p
+}
+
+// addKnown sums amounts where -1 means unknown and is sticky.
+func addKnown(a, b float64) float64 {
+	if a < 0 || b < 0 {
+		return -1
+	}
+	return a + b
+}
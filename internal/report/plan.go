@@ -0,0 +1,113 @@
+package report
+
+import (
+	"math"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// PlanMonth compares API-equivalent cost with subscription fees for one
+// calendar month.
+type PlanMonth struct {
+	Month     string  // YYYY-MM
+	Users     int     // seats the fee is paid for
+	Cost      float64 // API-equivalent cost of all records in the month
+	Fee       float64 // total plan fee: per-seat fee × Users, prorated for partial months
+	BreakEven string  // YYYY-MM-DD when cumulative cost reached Fee; empty if not reached
+	Days      int     // days of the month inside the report range
+	MonthDays int     // days in the calendar month
+}
+
+// Partial reports whether the report range covers only part of the month,
+// so Fee is prorated.
+func (p *PlanMonth) Partial() bool {
+	return p.Days < p.MonthDays
+}
+
+// PerUser returns the API-equivalent cost per seat.
+func (p *PlanMonth) PerUser() float64 {
+	if p.Users == 0 {
+		return p.Cost
+	}
+	return p.Cost / float64(p.Users)
+}
+
+// Multiple returns how many times the fee the usage would have cost at API
+// prices; above 1 the subscription pays off.
+func (p *PlanMonth) Multiple() float64 {
+	if p.Fee == 0 {
+		return 0
+	}
+	return p.Cost / p.Fee
+}
+
+// ComparePlan groups record costs by month and compares each month against
+// a plan with the given per-seat monthly fee and number of seats. Months
+// that the report range [since, until] covers only in part are compared
+// against the fee for the days covered; a zero since or until leaves that
+// end of the month whole. Records are expected in time order, as returned
+// by the parser. Records with an unknown model contribute no cost. When
+// records are labelled with users, as merged from bundles, each labelled
+// user is a seat and users is ignored.
+func ComparePlan(records []parser.Record, fee float64, users int, since, until time.Time) []PlanMonth {
+	labelled := map[string]bool{}
+	for i := range records {
+		if u := records[i].User; u != "" {
+			labelled[u] = true
+		}
+	}
+	users = max(users, 1)
+	if len(labelled) > 0 {
+		users = len(labelled)
+	}
+	var months []PlanMonth
+	for i := range records {
+		r := &records[i]
+		month := r.Time.Format("2006-01")
+		if len(months) == 0 || months[len(months)-1].Month != month {
+			days, monthDays := coveredDays(r.Time, since, until)
+			months = append(months, PlanMonth{
+				Month:     month,
+				Users:     users,
+				Fee:       fee * float64(users) * float64(days) / float64(monthDays),
+				Days:      days,
+				MonthDays: monthDays,
+			})
+		}
+		m := &months[len(months)-1]
+
+		m.Cost += max(RecordCost(r), 0)
+		if m.BreakEven == "" && m.Fee > 0 && m.Cost >= m.Fee {
+			m.BreakEven = r.Time.Format("2006-01-02")
+		}
+	}
+	return months
+}
+
+// coveredDays returns how many days of t's calendar month fall within
+// [since, until], and the number of days in the month.
+func coveredDays(t, since, until time.Time) (days, monthDays int) {
+	loc := t.Location()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+	from, to := start, end
+	if !since.IsZero() {
+		s := since.In(loc)
+		if d := time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, loc); d.After(from) {
+			from = d
+		}
+	}
+	if !until.IsZero() {
+		u := until.In(loc)
+		if d := time.Date(u.Year(), u.Month(), u.Day()+1, 0, 0, 0, 0, loc); d.Before(to) {
+			to = d
+		}
+	}
+	return max(dayCount(from, to), 1), dayCount(start, end)
+}
+
+// dayCount counts the days between two midnights, allowing for DST shifts.
+func dayCount(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,57 @@
+package report
+
+// ComputeRates sets CostPerHour, TokensPerMinute and OutputRatio on every
+// row, child and Total. Time-based rates need active session time, so they
+// stay zero for rows without it. Per-model rows of a detailed report share
+// their key's session time: the first one carries the rates of the whole
+// key, the others only their own OutputRatio.
+func (r *Report) ComputeRates() {
+	sums := map[string]*Row{}
+	for i := range r.Rows {
+		row := &r.Rows[i]
+		if s, ok := sums[row.Key]; ok {
+			s.Add(row)
+		} else {
+			s := *row
+			sums[row.Key] = &s
+		}
+	}
+	seen := map[string]bool{}
+	for i := range r.Rows {
+		row := &r.Rows[i]
+		setRates(row, row)
+		if row.Model != "" {
+			row.CostPerHour, row.TokensPerMinute = 0, 0
+			if !seen[row.Key] {
+				setTimeRates(row, sums[row.Key])
+			}
+		}
+		seen[row.Key] = true
+	}
+	setRates(&r.Total, &r.Total)
+}
+
+// setRates sets the rates of row from the totals of src, recursing into
+// row's children.
+func setRates(row, src *Row) {
+	if src.Input > 0 {
+		row.OutputRatio = float64(src.Output) / float64(src.Input)
+	}
+	setTimeRates(row, src)
+	for i := range row.Children {
+		setRates(&row.Children[i], &row.Children[i])
+	}
+}
+
+func setTimeRates(row, src *Row) {
+	row.CostPerHour, row.TokensPerMinute = 0, 0
+	if src.Active <= 0 {
+		return
+	}
+	row.TokensPerMinute = float64(src.Input+src.Output+src.CacheWrite+src.CacheRead) / src.Active.Minutes()
+	if src.Cost < 0 {
+		row.CostPerHour = -1
+	} else {
+		row.CostPerHour = src.Cost / src.Active.Hours()
+	}
+}
@@ -0,0 +1,51 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+// RecordOrder selects how individual records are listed.
+type RecordOrder string
+
+const (
+	ByTime   RecordOrder = "time"   // oldest first
+	ByCost   RecordOrder = "cost"   // most expensive first
+	ByTokens RecordOrder = "tokens" // most tokens first
+)
+
+// ParseRecordOrder validates a record sort order.
+func ParseRecordOrder(s string) (RecordOrder, error) {
+	switch o := RecordOrder(s); o {
+	case ByTime, ByCost, ByTokens:
+		return o, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q (want time, cost or tokens)", s)
+	}
+}
+
+// Tokens returns all tokens of r: input, output and cache.
+func Tokens(r *parser.Record) int {
+	return r.Input + r.Output + r.CacheWrite + r.CacheRead
+}
+
+// SortRecords sorts records in place by order. Ties keep time order.
+// Records with an unknown model sort as free.
+func SortRecords(records []parser.Record, order RecordOrder) {
+	slices.SortStableFunc(records, func(a, b parser.Record) int {
+		switch order {
+		case ByCost:
+			if c := cmp.Compare(max(RecordCost(&b), 0), max(RecordCost(&a), 0)); c != 0 {
+				return c
+			}
+		case ByTokens:
+			if c := cmp.Compare(Tokens(&b), Tokens(&a)); c != 0 {
+				return c
+			}
+		}
+		return a.Time.Compare(b.Time)
+	})
+}
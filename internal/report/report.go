@@ -2,7 +2,9 @@ package report
 
 import (
 	"cmp"
+	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/zulerne/ccost/internal/parser"
@@ -11,7 +13,7 @@ import (
 
 // Row is a single aggregated line in the report.
 type Row struct {
-	Key        string // date (YYYY-MM-DD) or project name
+	Key        string // value of the grouping dimension, e.g. date (YYYY-MM-DD) or project
 	Model      string // populated only in detailed (--models) mode
 	Input      int
 	Output     int
@@ -19,48 +21,287 @@ type Row struct {
 	CacheRead  int
 	Cost       float64       // -1 if contains unknown model with non-zero tokens
 	Duration   time.Duration // session time; zero for per-model detail rows
+	Active     time.Duration // session time excluding idle gaps; zero for per-model detail rows
+
+	WebSearches    int     // server-side web search requests
+	WebFetches     int     // server-side web fetch requests
+	ServerToolCost float64 // per-request server tool fees, included in Cost
+
+	CacheSaved        float64 // cost avoided by cache reads vs. full input price
+	CacheWritePremium float64 // extra paid for cache writes over input price
+
+	AgentCost float64 // cost of subagent requests, included in Cost
+	Agents    int     // distinct subagent logs contributing to the row
+	ToolCalls int     // tool invocations made by the row's responses
+
+	Children []Row // next grouping level in nested reports; the row is their subtotal
+
+	CumCost   float64 // running cost through this key, set by Cumulate; -1 once unknown
+	CumTokens int     // running token count through this key, set by Cumulate
+
+	CostPerHour     float64 // cost per active hour, set by ComputeRates; -1 if Cost is unknown
+	TokensPerMinute float64 // tokens per active minute, set by ComputeRates
+	OutputRatio     float64 // output tokens per uncached input token, set by ComputeRates
+}
+
+// CacheHitRatio is the share of input-side tokens served from cache.
+func (r *Row) CacheHitRatio() float64 {
+	total := r.Input + r.CacheWrite + r.CacheRead
+	if total == 0 {
+		return 0
+	}
+	return float64(r.CacheRead) / float64(total)
+}
+
+// CacheROI is the net benefit of caching: read savings minus write premium.
+func (r *Row) CacheROI() float64 {
+	return r.CacheSaved - r.CacheWritePremium
+}
+
+// MainCost is the cost of requests made by the main session, or -1 if Cost
+// is unknown.
+func (r *Row) MainCost() float64 {
+	if r.Cost < 0 {
+		return -1
+	}
+	return max(r.Cost-r.AgentCost, 0)
 }
 
 // Report holds aggregated rows and a total.
 type Report struct {
 	Rows  []Row
 	Total Row
+	Plan  []PlanMonth // optional subscription comparison, one entry per month
+	Dims  []Dimension // grouping levels of a nested report, outermost first
+
+	Cumulative []CumulativePoint // running totals per key, set by Cumulate
 }
 
-// ByDate groups records by date, merging all models.
-func ByDate(records []parser.Record, sessions []parser.Session) Report {
-	return aggregate(records, sessions, func(r parser.Record) string {
+// Dimension names a record attribute that reports can group by.
+type Dimension string
+
+const (
+	Date    Dimension = "date"
+	Project Dimension = "project"
+	Repo    Dimension = "repo"
+	Branch  Dimension = "branch"
+	Model   Dimension = "model"
+	Session Dimension = "session"
+	Agent   Dimension = "agent"
+	Tool    Dimension = "tool"
+	User    Dimension = "user"
+)
+
+// Dimensions lists every supported grouping in display order.
+var Dimensions = []Dimension{Date, Project, Repo, Branch, Model, Session, Agent, Tool, User}
+
+// noBranch labels records made outside any git branch.
+const noBranch = "(none)"
+
+// mainAgent labels requests made by the main session rather than a subagent.
+const mainAgent = "(main)"
+
+// noTool labels responses that invoked no tool.
+const noTool = "(none)"
+
+// localUser labels records read from local logs rather than a merged bundle.
+const localUser = "(local)"
+
+// noSession labels records whose session ID was dropped by --redact.
+const noSession = "(redacted)"
+
+// ParseDimension validates a dimension name.
+func ParseDimension(s string) (Dimension, error) {
+	d := Dimension(strings.ToLower(s))
+	if !slices.Contains(Dimensions, d) {
+		return "", fmt.Errorf("unknown grouping %q (want one of %s)", s, joinDimensions(Dimensions))
+	}
+	return d, nil
+}
+
+func joinDimensions(dims []Dimension) string {
+	names := make([]string, len(dims))
+	for i, d := range dims {
+		names[i] = string(d)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Header returns the column title for the dimension.
+func (d Dimension) Header() string {
+	if d == "" {
+		return ""
+	}
+	return strings.ToUpper(string(d[:1])) + string(d[1:])
+}
+
+// RecordKey returns the group key of r under d.
+func (d Dimension) RecordKey(r *parser.Record) string {
+	switch d {
+	case Project:
+		return r.Project
+	case Repo:
+		return r.Repo
+	case Branch:
+		return cmp.Or(r.Branch, noBranch)
+	case Model:
+		return r.Model
+	case Session:
+		return cmp.Or(r.Session, noSession)
+	case Agent:
+		return cmp.Or(r.AgentID, mainAgent)
+	case Tool:
+		// Records are split per tool before grouping; see splitByTool.
+		if len(r.Tools) == 0 {
+			return noTool
+		}
+		return r.Tools[0]
+	case User:
+		return cmp.Or(r.User, localUser)
+	default:
 		return r.Time.Format("2006-01-02")
-	}, func(s parser.Session) string {
+	}
+}
+
+// SessionKey returns the group key of s under d.
+func (d Dimension) SessionKey(s *parser.Session) string {
+	switch d {
+	case Project:
+		return s.Project
+	case Repo:
+		return s.Repo
+	case Branch:
+		return cmp.Or(s.Branch, noBranch)
+	case Model, Tool:
+		// Session time is not attributable to a single model or tool.
+		return ""
+	case Session:
+		return cmp.Or(s.ID, noSession)
+	case Agent:
+		// Session time is measured on main logs only.
+		return mainAgent
+	case User:
+		return cmp.Or(s.User, localUser)
+	default:
 		return s.Date
-	}, false)
+	}
+}
+
+// By groups records by dim. When detailed is true, each group is further
+// split by model. Grouping by Tool attributes each response's tokens and
+// cost to the tools it invoked, in proportion to their number of calls.
+func By(dim Dimension, records []parser.Record, sessions []parser.Session, detailed bool) Report {
+	if dim == Tool {
+		records = splitByTool(records)
+	}
+	return aggregate(records, sessions, dim.RecordKey, dim.SessionKey, detailed)
+}
+
+// Nested groups records by each of dims in turn: rows group by dims[0] and
+// each row's Children group its records by dims[1], and so on. Every row is
+// the subtotal of its children.
+func Nested(dims []Dimension, records []parser.Record, sessions []parser.Session) Report {
+	if slices.Contains(dims, Tool) {
+		// Split once so every record carries a single tool key at all levels.
+		records = splitByTool(records)
+	}
+	rpt := Report{Rows: nest(dims, records, sessions), Dims: dims}
+	rpt.Total = By(dims[0], records, sessions, false).Total
+	return rpt
+}
+
+func nest(dims []Dimension, records []parser.Record, sessions []parser.Session) []Row {
+	dim := dims[0]
+	rows := By(dim, records, sessions, false).Rows
+	if len(dims) == 1 {
+		return rows
+	}
+	for i := range rows {
+		key := rows[i].Key
+		var subRecords []parser.Record
+		for j := range records {
+			if dim.RecordKey(&records[j]) == key {
+				subRecords = append(subRecords, records[j])
+			}
+		}
+		var subSessions []parser.Session
+		for j := range sessions {
+			if dim.SessionKey(&sessions[j]) == key {
+				subSessions = append(subSessions, sessions[j])
+			}
+		}
+		rows[i].Children = nest(dims[1:], subRecords, subSessions)
+	}
+	return rows
+}
+
+// splitByTool returns one record per distinct tool of each input record,
+// carrying that tool's calls and its share of the tokens. Token remainders
+// and server tool requests go to the first tool so totals are preserved.
+func splitByTool(records []parser.Record) []parser.Record {
+	out := make([]parser.Record, 0, len(records))
+	for i := range records {
+		r := &records[i]
+		if len(r.Tools) == 0 {
+			out = append(out, *r)
+			continue
+		}
+
+		calls := map[string]int{}
+		var names []string
+		for _, t := range r.Tools {
+			if calls[t] == 0 {
+				names = append(names, t)
+			}
+			calls[t]++
+		}
+
+		// Later tools get their proportional share; the first tool takes
+		// whatever is left.
+		n := len(r.Tools)
+		lead := len(out)
+		out = append(out, *r)
+		for k, name := range names {
+			c := calls[name]
+			if k == 0 {
+				out[lead].Tools = slices.Repeat([]string{name}, c)
+				continue
+			}
+			s := *r
+			s.Tools = slices.Repeat([]string{name}, c)
+			s.Input = r.Input * c / n
+			s.Output = r.Output * c / n
+			s.CacheWrite = r.CacheWrite * c / n
+			s.CacheRead = r.CacheRead * c / n
+			s.WebSearches, s.WebFetches = 0, 0
+			out = append(out, s)
+			out[lead].Input -= s.Input
+			out[lead].Output -= s.Output
+			out[lead].CacheWrite -= s.CacheWrite
+			out[lead].CacheRead -= s.CacheRead
+		}
+	}
+	return out
+}
+
+// ByDate groups records by date, merging all models.
+func ByDate(records []parser.Record, sessions []parser.Session) Report {
+	return By(Date, records, sessions, false)
 }
 
 // ByDateDetailed groups records by date + model.
 func ByDateDetailed(records []parser.Record, sessions []parser.Session) Report {
-	return aggregate(records, sessions, func(r parser.Record) string {
-		return r.Time.Format("2006-01-02")
-	}, func(s parser.Session) string {
-		return s.Date
-	}, true)
+	return By(Date, records, sessions, true)
 }
 
 // ByProject groups records by project, merging all models.
 func ByProject(records []parser.Record, sessions []parser.Session) Report {
-	return aggregate(records, sessions, func(r parser.Record) string {
-		return r.Project
-	}, func(s parser.Session) string {
-		return s.Project
-	}, false)
+	return By(Project, records, sessions, false)
 }
 
 // ByProjectDetailed groups records by project + model.
 func ByProjectDetailed(records []parser.Record, sessions []parser.Session) Report {
-	return aggregate(records, sessions, func(r parser.Record) string {
-		return r.Project
-	}, func(s parser.Session) string {
-		return s.Project
-	}, true)
+	return By(Project, records, sessions, true)
 }
 
 type groupKey struct {
@@ -71,19 +312,22 @@ type groupKey struct {
 type accum struct {
 	Row
 	hasUnknown bool
+	agents     map[string]bool // session/agent IDs seen
 }
 
 func aggregate(
 	records []parser.Record,
 	sessions []parser.Session,
-	keyFn func(parser.Record) string,
-	sessionKeyFn func(parser.Session) string,
+	keyFn func(*parser.Record) string,
+	sessionKeyFn func(*parser.Session) string,
 	detailed bool,
 ) Report {
 	groups := map[groupKey]*accum{}
 	var keys []groupKey
+	allAgents := map[string]bool{}
 
-	for _, r := range records {
+	for i := range records {
+		r := &records[i]
 		k := groupKey{key: keyFn(r)}
 		if detailed {
 			k.model = r.Model
@@ -98,19 +342,40 @@ func aggregate(
 		a.Output += r.Output
 		a.CacheWrite += r.CacheWrite
 		a.CacheRead += r.CacheRead
+		a.ToolCalls += len(r.Tools)
+
+		a.WebSearches += r.WebSearches
+		a.WebFetches += r.WebFetches
+		a.ServerToolCost += pricing.ServerToolCost(r.WebSearches, r.WebFetches)
 
-		c := pricing.Cost(r.Model, r.Input, r.Output, r.CacheWrite, r.CacheRead)
+		c := RecordCost(r)
 		if c >= 0 {
 			a.Cost += c
 		} else {
 			a.hasUnknown = true
 		}
+		if r.IsSubagent {
+			a.AgentCost += max(c, 0)
+			id := r.Session + "/" + r.AgentID
+			if a.agents == nil {
+				a.agents = map[string]bool{}
+			}
+			a.agents[id] = true
+			allAgents[id] = true
+		}
+		if saved, premium, ok := pricing.CacheSavings(r.Model, r.CacheWrite, r.CacheRead); ok {
+			a.CacheSaved += saved
+			a.CacheWritePremium += premium
+		}
 	}
 
 	// Aggregate session durations per key (not per model).
 	durations := map[string]time.Duration{}
-	for _, s := range sessions {
-		durations[sessionKeyFn(s)] += s.Duration
+	active := map[string]time.Duration{}
+	for i := range sessions {
+		k := sessionKeyFn(&sessions[i])
+		durations[k] += sessions[i].Duration
+		active[k] += sessions[i].Active
 	}
 
 	slices.SortFunc(keys, func(a, b groupKey) int {
@@ -134,14 +399,23 @@ func aggregate(
 		// Assign duration: once per key group (first row only in detailed mode).
 		if !detailed || !seen[k.key] {
 			a.Duration = durations[k.key]
+			a.Active = active[k.key]
 			seen[k.key] = true
 		}
+		a.Agents = len(a.agents)
 		rows = append(rows, a.Row)
 
 		total.Input += a.Input
 		total.Output += a.Output
 		total.CacheWrite += a.CacheWrite
 		total.CacheRead += a.CacheRead
+		total.CacheSaved += a.CacheSaved
+		total.CacheWritePremium += a.CacheWritePremium
+		total.AgentCost += a.AgentCost
+		total.WebSearches += a.WebSearches
+		total.WebFetches += a.WebFetches
+		total.ServerToolCost += a.ServerToolCost
+		total.ToolCalls += a.ToolCalls
 		if a.hasUnknown {
 			totalHasUnknown = true
 		} else {
@@ -153,9 +427,24 @@ func aggregate(
 		total.Cost = -1
 	}
 
+	total.Agents = len(allAgents)
+
 	for _, d := range durations {
 		total.Duration += d
 	}
+	for _, d := range active {
+		total.Active += d
+	}
 
 	return Report{Rows: rows, Total: total}
 }
+
+// RecordCost returns the cost of r in USD, including server tool fees, or
+// -1 if its model is unknown.
+func RecordCost(r *parser.Record) float64 {
+	c := pricing.Cost(r.Model, r.Input, r.Output, r.CacheWrite, r.CacheRead)
+	if c < 0 {
+		return -1
+	}
+	return c + pricing.ServerToolCost(r.WebSearches, r.WebFetches)
+}
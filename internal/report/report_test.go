@@ -1,7 +1,10 @@
 package report
 
 import (
+	"fmt"
 	"math"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -245,3 +248,627 @@ func TestByProjectWithDuration(t *testing.T) {
 		t.Errorf("expected total duration 60m, got %v", rpt.Total.Duration)
 	}
 }
+
+func TestByProjectActive(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "alpha", Input: 100},
+		{Time: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "beta", Input: 200},
+	}
+	sessions := []parser.Session{
+		{Date: "2026-02-14", Project: "alpha", Duration: 20 * time.Minute, Active: 15 * time.Minute},
+		{Date: "2026-02-14", Project: "alpha", Duration: 5 * time.Minute, Active: 5 * time.Minute},
+		{Date: "2026-02-15", Project: "beta", Duration: 40 * time.Minute, Active: 10 * time.Minute},
+	}
+
+	rpt := ByProject(records, sessions)
+	if rpt.Rows[0].Active != 20*time.Minute {
+		t.Errorf("expected alpha active 20m, got %v", rpt.Rows[0].Active)
+	}
+	if rpt.Rows[1].Active != 10*time.Minute {
+		t.Errorf("expected beta active 10m, got %v", rpt.Rows[1].Active)
+	}
+	if rpt.Total.Active != 30*time.Minute || rpt.Total.Duration != 65*time.Minute {
+		t.Errorf("expected total active 30m of 65m, got %v of %v", rpt.Total.Active, rpt.Total.Duration)
+	}
+}
+
+func TestByBranch(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Branch: "main", Input: 100},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Branch: "feat/x", Input: 200},
+		{Time: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Branch: "main", Input: 300},
+		{Time: time.Date(2026, 2, 15, 11, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 400},
+	}
+	sessions := []parser.Session{
+		{Date: "2026-02-14", Branch: "main", Duration: time.Hour},
+		{Date: "2026-02-15", Branch: "main", Duration: 30 * time.Minute},
+	}
+
+	rpt := By(Branch, records, sessions, false)
+	if len(rpt.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rpt.Rows))
+	}
+	want := map[string]int{"(none)": 400, "feat/x": 200, "main": 400}
+	for _, r := range rpt.Rows {
+		if r.Input != want[r.Key] {
+			t.Errorf("branch %q: expected input %d, got %d", r.Key, want[r.Key], r.Input)
+		}
+		if r.Key == "main" && r.Duration != 90*time.Minute {
+			t.Errorf("expected main duration 1h30m, got %v", r.Duration)
+		}
+	}
+}
+
+func TestParseDimension(t *testing.T) {
+	for _, s := range []string{"date", "Project", "repo", "branch"} {
+		if _, err := ParseDimension(s); err != nil {
+			t.Errorf("ParseDimension(%q): %v", s, err)
+		}
+	}
+	if _, err := ParseDimension("weekday"); err == nil {
+		t.Error("expected error for unknown dimension")
+	}
+	if h := Repo.Header(); h != "Repo" {
+		t.Errorf("expected header 'Repo', got %q", h)
+	}
+}
+
+func TestByHour(t *testing.T) {
+	records := []parser.Record{
+		// 2026-02-16 is a Monday.
+		{Time: time.Date(2026, 2, 16, 9, 15, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 16, 9, 45, 0, 0, time.UTC), Model: "claude-opus-4-6", Output: 1_000_000},
+		{Time: time.Date(2026, 2, 22, 23, 0, 0, 0, time.UTC), Model: "unknown-model", Input: 500},
+	}
+
+	cost := ByHour(records, MetricCost)
+	if !almostEqual(cost.Cells[0][9], 30.0) {
+		t.Errorf("expected Monday 09:00 cost 30.0, got %f", cost.Cells[0][9])
+	}
+	if cost.Cells[6][23] != 0 {
+		t.Errorf("expected unknown model to add no cost, got %f", cost.Cells[6][23])
+	}
+	if !almostEqual(cost.Max(), 30.0) || !almostEqual(cost.RowTotal(0), 30.0) {
+		t.Errorf("expected max and Monday total 30.0, got %f and %f", cost.Max(), cost.RowTotal(0))
+	}
+
+	tokens := ByHour(records, MetricTokens)
+	if tokens.Cells[0][9] != 2_000_000 {
+		t.Errorf("expected Monday 09:00 tokens 2000000, got %f", tokens.Cells[0][9])
+	}
+	if tokens.Cells[6][23] != 500 {
+		t.Errorf("expected Sunday 23:00 tokens 500, got %f", tokens.Cells[6][23])
+	}
+
+	if _, err := ParseMetric("requests"); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+}
+
+func TestCacheMetrics(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 100_000, CacheWrite: 100_000, CacheRead: 800_000},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-sonnet-4-5", CacheRead: 1_000_000},
+	}
+	rpt := ByDate(records, nil)
+	row := rpt.Rows[0]
+
+	// opus: 0.8M * 4.50 = 3.60 saved, 0.1M * 5 = 0.50 premium; sonnet: 1M * 2.70 = 2.70 saved.
+	if !almostEqual(row.CacheSaved, 6.30) {
+		t.Errorf("expected saved 6.30, got %f", row.CacheSaved)
+	}
+	if !almostEqual(row.CacheWritePremium, 0.50) {
+		t.Errorf("expected write premium 0.50, got %f", row.CacheWritePremium)
+	}
+	if !almostEqual(row.CacheROI(), 5.80) {
+		t.Errorf("expected ROI 5.80, got %f", row.CacheROI())
+	}
+	if !almostEqual(row.CacheHitRatio(), 1.8/2.0) {
+		t.Errorf("expected hit ratio 0.9, got %f", row.CacheHitRatio())
+	}
+	if !almostEqual(rpt.Total.CacheSaved, 6.30) {
+		t.Errorf("expected total saved 6.30, got %f", rpt.Total.CacheSaved)
+	}
+
+	var empty Row
+	if empty.CacheHitRatio() != 0 {
+		t.Error("expected zero hit ratio for empty row")
+	}
+}
+
+func TestComparePlan(t *testing.T) {
+	records := []parser.Record{
+		// claude-opus-4-6: 1M input = $5.
+		{Time: time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 10, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 4_000_000},
+		{Time: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), Model: "unknown-model", Input: 1_000_000},
+		{Time: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+	}
+
+	months := ComparePlan(records, 5, 2, time.Time{}, time.Time{})
+	if len(months) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(months))
+	}
+
+	feb := &months[0]
+	if feb.Month != "2026-02" || feb.Fee != 10 || feb.Users != 2 {
+		t.Errorf("unexpected February plan: %+v", *feb)
+	}
+	if !almostEqual(feb.Cost, 30) || !almostEqual(feb.PerUser(), 15) || !almostEqual(feb.Multiple(), 3) {
+		t.Errorf("expected cost 30, per user 15, multiple 3; got %v, %v, %v", feb.Cost, feb.PerUser(), feb.Multiple())
+	}
+	if feb.BreakEven != "2026-02-10" {
+		t.Errorf("expected break-even 2026-02-10, got %q", feb.BreakEven)
+	}
+
+	mar := &months[1]
+	if !almostEqual(mar.Cost, 5) || mar.BreakEven != "" {
+		t.Errorf("expected March cost 5 without break-even, got %v and %q", mar.Cost, mar.BreakEven)
+	}
+}
+
+func TestComparePlanUsers(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000, User: "alice"},
+		{Time: time.Date(2026, 2, 4, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000, User: "bob"},
+		{Time: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000, User: "carol"},
+	}
+
+	// Labelled users are the seats, whatever the seat count given, and stay
+	// seats in months they didn't use.
+	months := ComparePlan(records, 5, 10, time.Time{}, time.Time{})
+	if len(months) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(months))
+	}
+	if feb := &months[0]; feb.Users != 3 || feb.Fee != 15 || !almostEqual(feb.PerUser(), 10.0/3) {
+		t.Errorf("expected 3 seats for $15 and $3.33 per user, got %+v", *feb)
+	}
+	if mar := &months[1]; mar.Users != 3 || !almostEqual(mar.PerUser(), 5.0/3) {
+		t.Errorf("expected 3 seats in March, got %+v", *mar)
+	}
+}
+
+func TestComparePlanPartialMonths(t *testing.T) {
+	records := []parser.Record{
+		// claude-opus-4-6: 1M input = $5.
+		{Time: time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 4, 2, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+	}
+	since := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 4, 10, 23, 59, 59, 0, time.UTC)
+
+	// $28 per month: $1 per day in February.
+	months := ComparePlan(records, 28, 1, since, until)
+	if len(months) != 3 {
+		t.Fatalf("expected 3 months, got %d", len(months))
+	}
+	feb, mar, apr := &months[0], &months[1], &months[2]
+	if !feb.Partial() || feb.Days != 14 || feb.MonthDays != 28 || !almostEqual(feb.Fee, 14) {
+		t.Errorf("expected February prorated to 14 of 28 days ($14), got %+v", *feb)
+	}
+	if mar.Partial() || !almostEqual(mar.Fee, 28) {
+		t.Errorf("expected the full March fee, got %+v", *mar)
+	}
+	if !apr.Partial() || apr.Days != 10 || !almostEqual(apr.Fee, 28*10.0/30) {
+		t.Errorf("expected April prorated to 10 of 30 days, got %+v", *apr)
+	}
+	// The prorated fee is what break-even is measured against.
+	if apr.BreakEven != "" || feb.BreakEven != "" {
+		t.Errorf("expected no break-even below the prorated fee, got %q and %q", feb.BreakEven, apr.BreakEven)
+	}
+	if months = ComparePlan(records[2:], 28, 1, since, time.Date(2026, 4, 3, 12, 0, 0, 0, time.UTC)); months[0].BreakEven != "2026-04-02" {
+		t.Errorf("expected break-even against three days' fee, got %+v", months[0])
+	}
+}
+
+func TestSplitAgents(t *testing.T) {
+	ts := time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC)
+	records := []parser.Record{
+		// claude-opus-4-6: 1M input = $5.
+		{Time: ts, Model: "claude-opus-4-6", Project: "shop", Session: "s1", Input: 1_000_000},
+		{Time: ts, Model: "claude-opus-4-6", Project: "shop", Session: "s1", IsSubagent: true, AgentID: "agent-a", Input: 2_000_000},
+		{Time: ts, Model: "claude-opus-4-6", Project: "shop", Session: "s1", IsSubagent: true, AgentID: "agent-a", Input: 1_000_000},
+		{Time: ts, Model: "claude-opus-4-6", Project: "blog", Session: "s2", IsSubagent: true, AgentID: "agent-b", Input: 1_000_000},
+	}
+	sessions := []parser.Session{{ID: "s1", Date: "2026-02-14", Project: "shop", Duration: time.Hour}}
+
+	rpt := ByProject(records, sessions)
+	shop := &rpt.Rows[1]
+	if shop.Key != "shop" || !almostEqual(shop.AgentCost, 15) || !almostEqual(shop.MainCost(), 5) || shop.Agents != 1 {
+		t.Errorf("unexpected shop split: agent %v, main %v, agents %d", shop.AgentCost, shop.MainCost(), shop.Agents)
+	}
+	if !almostEqual(rpt.Total.AgentCost, 20) || rpt.Total.Agents != 2 {
+		t.Errorf("expected total agent cost 20 over 2 agents, got %v over %d", rpt.Total.AgentCost, rpt.Total.Agents)
+	}
+
+	byAgent := By(Agent, records, sessions, false)
+	if len(byAgent.Rows) != 3 {
+		t.Fatalf("expected 3 agent rows, got %d", len(byAgent.Rows))
+	}
+	mainRow := &byAgent.Rows[0]
+	if mainRow.Key != "(main)" || !almostEqual(mainRow.Cost, 5) || mainRow.Duration != time.Hour {
+		t.Errorf("unexpected main row: %+v", *mainRow)
+	}
+	if byAgent.Rows[1].Key != "agent-a" || !almostEqual(byAgent.Rows[1].Cost, 15) {
+		t.Errorf("unexpected agent-a row: %+v", byAgent.Rows[1])
+	}
+}
+
+func TestByTool(t *testing.T) {
+	ts := time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC)
+	records := []parser.Record{
+		// Two Bash calls and one Read: Read gets a third, rounded down, and
+		// Bash the rest.
+		{Time: ts, Model: "claude-opus-4-6", Tools: []string{"Bash", "Read", "Bash"}, Input: 1000, Output: 301},
+		{Time: ts, Model: "claude-opus-4-6", Input: 50},
+	}
+
+	rpt := By(Tool, records, nil, false)
+	if len(rpt.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rpt.Rows))
+	}
+	byKey := map[string]Row{}
+	for _, r := range rpt.Rows {
+		byKey[r.Key] = r
+	}
+	if r := byKey["Bash"]; r.Input != 667 || r.Output != 201 || r.ToolCalls != 2 {
+		t.Errorf("unexpected Bash row: %+v", r)
+	}
+	if r := byKey["Read"]; r.Input != 333 || r.Output != 100 || r.ToolCalls != 1 {
+		t.Errorf("unexpected Read row: %+v", r)
+	}
+	if r := byKey["(none)"]; r.Input != 50 || r.ToolCalls != 0 {
+		t.Errorf("unexpected (none) row: %+v", r)
+	}
+
+	plain := ByDate(records, nil)
+	if rpt.Total.Input != plain.Total.Input || rpt.Total.Output != plain.Total.Output {
+		t.Errorf("expected tool split to preserve totals, got %+v vs %+v", rpt.Total, plain.Total)
+	}
+	if !almostEqual(rpt.Total.Cost, plain.Total.Cost) {
+		t.Errorf("expected tool split to preserve cost, got %v vs %v", rpt.Total.Cost, plain.Total.Cost)
+	}
+	if plain.Total.ToolCalls != 3 {
+		t.Errorf("expected 3 tool calls in date report, got %d", plain.Total.ToolCalls)
+	}
+}
+
+func TestServerToolCost(t *testing.T) {
+	ts := time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC)
+	records := []parser.Record{
+		// claude-opus-4-6: 1M input = $5, plus 100 searches = $1.
+		{Time: ts, Model: "claude-opus-4-6", Input: 1_000_000, WebSearches: 100, WebFetches: 4},
+		{Time: ts, Model: "claude-opus-4-6", Input: 1_000_000},
+	}
+
+	rpt := ByDate(records, nil)
+	if rpt.Total.WebSearches != 100 || rpt.Total.WebFetches != 4 {
+		t.Errorf("expected 100 searches and 4 fetches, got %d and %d", rpt.Total.WebSearches, rpt.Total.WebFetches)
+	}
+	if !almostEqual(rpt.Total.ServerToolCost, 1) {
+		t.Errorf("expected server tool cost 1, got %v", rpt.Total.ServerToolCost)
+	}
+	if !almostEqual(rpt.Total.Cost, 11) {
+		t.Errorf("expected cost 11 including server tools, got %v", rpt.Total.Cost)
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Session: "a", Input: 1000},
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Session: "b", Input: 5000},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Session: "c", Output: 1000},
+		{Time: time.Date(2026, 2, 14, 9, 0, 0, 0, time.UTC), Model: "unknown-model", Session: "d", Input: 9000},
+	}
+	sessions := func() string {
+		var b strings.Builder
+		for i := range records {
+			b.WriteString(records[i].Session)
+		}
+		return b.String()
+	}
+
+	// Opus output $25/M > haiku input $5K*1/M > opus input; unknown last.
+	SortRecords(records, ByCost)
+	if got := sessions(); got != "cbad" {
+		t.Errorf("expected cost order cbad, got %s", got)
+	}
+	SortRecords(records, ByTokens)
+	if got := sessions(); got != "dbca" {
+		t.Errorf("expected token order dbca (ties by time), got %s", got)
+	}
+	SortRecords(records, ByTime)
+	if got := sessions(); got != "dbca" {
+		t.Errorf("expected time order dbca, got %s", got)
+	}
+
+	if _, err := ParseRecordOrder("size"); err == nil {
+		t.Error("expected error for unknown sort")
+	}
+}
+
+func TestSortAndTop(t *testing.T) {
+	rpt := Report{
+		Rows: []Row{
+			{Key: "alpha", Model: "claude-haiku-4-5", Cost: 1, Input: 100, Duration: time.Hour},
+			{Key: "alpha", Model: "claude-opus-4-6", Cost: 4, Input: 100},
+			{Key: "beta", Model: "claude-opus-4-6", Cost: 3, Input: 900, Duration: time.Minute},
+			{Key: "gamma", Model: "claude-opus-4-6", Cost: -1, Input: 10},
+			{Key: "delta", Model: "claude-opus-4-6", Cost: 2, Input: 10},
+		},
+	}
+	keys := func() string {
+		out := make([]string, 0, len(rpt.Rows))
+		for i := range rpt.Rows {
+			out = append(out, rpt.Rows[i].Key)
+		}
+		return strings.Join(out, ",")
+	}
+
+	rpt.Sort(SortCost, true)
+	if got := keys(); got != "alpha,alpha,beta,delta,gamma" {
+		t.Errorf("expected cost desc with models kept together, got %s", got)
+	}
+	if rpt.Rows[0].Model != "claude-haiku-4-5" {
+		t.Errorf("expected model order kept within a key, got %s first", rpt.Rows[0].Model)
+	}
+	rpt.Sort(SortTokens, false)
+	if got := keys(); got != "delta,gamma,alpha,alpha,beta" {
+		t.Errorf("expected tokens asc, got %s", got)
+	}
+	rpt.Sort(SortDuration, true)
+	if got := keys(); got != "alpha,alpha,beta,delta,gamma" {
+		t.Errorf("expected duration desc, ties by key, got %s", got)
+	}
+
+	rpt.Sort(SortKey, false)
+	rpt.Top(2, SortKey)
+	if got := keys(); got != "alpha,alpha,beta,(other)" {
+		t.Fatalf("expected top 2 by cost in key order plus other, got %s", got)
+	}
+	other := rpt.Rows[3]
+	if other.Cost != -1 || other.Input != 20 {
+		t.Errorf("expected other to fold delta and gamma with unknown cost, got %+v", other)
+	}
+
+	if _, err := ParseSortField("size"); err == nil {
+		t.Error("expected error for unknown sort field")
+	}
+}
+
+func TestNested(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "shop", Input: 100},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Input: 200},
+		{Time: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "shop", Input: 400},
+		{Time: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "blog", Input: 800},
+	}
+	sessions := []parser.Session{
+		{Date: "2026-02-14", Project: "shop", Duration: 10 * time.Minute},
+		{Date: "2026-02-15", Project: "shop", Duration: 20 * time.Minute},
+		{Date: "2026-02-15", Project: "blog", Duration: 40 * time.Minute},
+	}
+
+	rpt := Nested([]Dimension{Project, Date, Model}, records, sessions)
+	if len(rpt.Rows) != 2 || rpt.Rows[0].Key != "blog" || rpt.Rows[1].Key != "shop" {
+		t.Fatalf("expected blog and shop at the top level, got %+v", rpt.Rows)
+	}
+	shop := &rpt.Rows[1]
+	if shop.Input != 700 || shop.Duration != 30*time.Minute {
+		t.Errorf("expected shop subtotal 700 tokens over 30m, got %d over %v", shop.Input, shop.Duration)
+	}
+	if len(shop.Children) != 2 || shop.Children[0].Key != "2026-02-14" || shop.Children[0].Duration != 10*time.Minute {
+		t.Fatalf("unexpected shop dates: %+v", shop.Children)
+	}
+	day := &shop.Children[0]
+	if len(day.Children) != 2 || day.Children[0].Key != "claude-haiku-4-5" || day.Children[0].Input != 200 {
+		t.Errorf("unexpected models under shop/2026-02-14: %+v", day.Children)
+	}
+	if rpt.Total.Input != 1500 || rpt.Total.Duration != 70*time.Minute {
+		t.Errorf("expected total 1500 tokens over 70m, got %d over %v", rpt.Total.Input, rpt.Total.Duration)
+	}
+
+	// Sorting applies within every level.
+	rpt.Sort(SortTokens, true)
+	if rpt.Rows[0].Key != "blog" || rpt.Rows[1].Children[0].Key != "2026-02-15" {
+		t.Errorf("expected nested levels sorted by tokens desc, got %s then %s", rpt.Rows[0].Key, rpt.Rows[1].Children[0].Key)
+	}
+}
+
+func TestPivot(t *testing.T) {
+	records := []parser.Record{
+		// claude-opus-4-6: 1M input = $5; claude-haiku-4-5: 1M input = $1.
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 2_000_000},
+		{Time: time.Date(2026, 2, 16, 10, 0, 0, 0, time.UTC), Model: "unknown-model", Input: 10},
+	}
+
+	p := PivotBy(Date, Model, MetricCost, records)
+	if got := strings.Join(p.ColKeys, ","); got != "claude-haiku-4-5,claude-opus-4-6,unknown-model" {
+		t.Fatalf("unexpected columns %s", got)
+	}
+	if len(p.RowKeys) != 3 || p.RowKeys[1] != "2026-02-15" {
+		t.Fatalf("unexpected rows %v", p.RowKeys)
+	}
+	if !almostEqual(p.Values[0][0], 1) || !almostEqual(p.Values[0][1], 5) || p.Values[1][0] != 0 || !almostEqual(p.Values[1][1], 10) {
+		t.Errorf("unexpected cells %v", p.Values)
+	}
+	if !almostEqual(p.RowTotals[0], 6) || !almostEqual(p.ColTotals[1], 15) {
+		t.Errorf("unexpected totals: rows %v, columns %v", p.RowTotals, p.ColTotals)
+	}
+	if p.Values[2][2] != -1 || p.ColTotals[2] != -1 || p.Total != -1 {
+		t.Errorf("expected unknown cost to propagate, got cell %v, column %v, total %v", p.Values[2][2], p.ColTotals[2], p.Total)
+	}
+
+	tokens := PivotBy(Model, Date, MetricTokens, records)
+	if tokens.Total != 4_000_010 || tokens.RowTotals[1] != 3_000_000 {
+		t.Errorf("unexpected token totals: %v, %v", tokens.Total, tokens.RowTotals)
+	}
+
+	if _, _, err := ParsePivot("date"); err == nil {
+		t.Error("expected error without a column dimension")
+	}
+	if _, _, err := ParsePivot("model,model"); err == nil {
+		t.Error("expected error for identical dimensions")
+	}
+	if r, c, err := ParsePivot("project,date"); err != nil || r != Project || c != Date {
+		t.Errorf("unexpected parse: %v, %v, %v", r, c, err)
+	}
+}
+
+func TestCumulate(t *testing.T) {
+	records := []parser.Record{
+		// claude-opus-4-6: 1M input = $5; claude-haiku-4-5: 1M input = $1.
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Input: 1_000_000},
+		{Time: time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Input: 2_000_000},
+	}
+
+	rpt := ByDateDetailed(records, nil)
+	rpt.Sort(SortKey, true) // display order must not change the running totals
+	rpt.Cumulate()
+
+	if len(rpt.Cumulative) != 2 || rpt.Cumulative[0].Date != "2026-02-14" {
+		t.Fatalf("expected 2 points in date order, got %+v", rpt.Cumulative)
+	}
+	if p := rpt.Cumulative[1]; !almostEqual(p.Cost, 16) || p.Tokens != 4_000_000 {
+		t.Errorf("expected 16 and 4M through 2026-02-15, got %+v", p)
+	}
+
+	// Rows: 2026-02-15 opus, then 2026-02-14 haiku and opus.
+	if !almostEqual(rpt.Rows[0].CumCost, 16) || rpt.Rows[1].CumTokens != 2_000_000 || rpt.Rows[2].CumTokens != 0 {
+		t.Errorf("expected running totals on the first row of each date only, got %+v", rpt.Rows)
+	}
+	if !almostEqual(rpt.Total.CumCost, 16) {
+		t.Errorf("expected total running cost 16, got %v", rpt.Total.CumCost)
+	}
+}
+
+func TestFindAnomalies(t *testing.T) {
+	// claude-haiku-4-5: 1M input = $1. Five quiet days of two $1 requests in
+	// one session each, then a day with a $20 runaway request.
+	var records []parser.Record
+	for d := 1; d <= 5; d++ {
+		for h := range 2 {
+			records = append(records, parser.Record{
+				Time: time.Date(2026, 2, d, 10+h, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5",
+				Project: "shop", Session: fmt.Sprintf("s%d", d), Input: 1_000_000 + d*10_000,
+			})
+		}
+	}
+	records = append(records,
+		parser.Record{Time: time.Date(2026, 2, 6, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Session: "s6", Input: 1_000_000},
+		parser.Record{Time: time.Date(2026, 2, 6, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Session: "s6", Input: 20_000_000, Tools: []string{"Bash", "Bash"}},
+		// Another project's spend is judged against its own baseline only.
+		parser.Record{Time: time.Date(2026, 2, 6, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "blog", Session: "b1", Input: 50_000_000},
+	)
+
+	found := FindAnomalies(records, AnomalyOptions{Factor: 3, Window: 14, MinCost: 0.5})
+	kinds := map[AnomalyKind]*Anomaly{}
+	for i := range found {
+		a := &found[i]
+		if a.Project != "shop" || a.Date != "2026-02-06" {
+			t.Errorf("unexpected anomaly %+v", *a)
+		}
+		kinds[a.Kind] = a
+	}
+	if len(found) != 3 || kinds[AnomalyDay] == nil || kinds[AnomalySession] == nil || kinds[AnomalyRequest] == nil {
+		t.Fatalf("expected one day, session and request anomaly, got %+v", found)
+	}
+	if a := kinds[AnomalyRequest]; !almostEqual(a.Cost, 20) || a.Detail != "haiku-4-5 · Bash" {
+		t.Errorf("unexpected request anomaly %+v", *a)
+	}
+	if a := kinds[AnomalySession]; a.Key != "s6" || a.Requests != 2 || !almostEqual(a.Median, 2.06) {
+		t.Errorf("unexpected session anomaly %+v", *a)
+	}
+
+	// A higher factor or a later From hides them.
+	if got := FindAnomalies(records, AnomalyOptions{Factor: 3, Window: 14, From: "2026-02-07"}); len(got) != 0 {
+		t.Errorf("expected nothing from 2026-02-07, got %+v", got)
+	}
+	if got := FindAnomalies(records, AnomalyOptions{Kinds: []AnomalyKind{AnomalyDay}, Factor: 3, Window: 2}); len(got) != 0 {
+		t.Errorf("expected no baseline with a 2-day window, got %+v", got)
+	}
+}
+
+func TestFindAnomaliesSpreadFloor(t *testing.T) {
+	// claude-haiku-4-5: 1M input = $1. A steady $1 a day has no deviation,
+	// so the spread is a tenth of the median: with factor 3, the
+	// threshold is $1.30.
+	var records []parser.Record
+	for d := 1; d <= 5; d++ {
+		records = append(records, parser.Record{
+			Time: time.Date(2026, 2, d, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Input: 1_000_000,
+		})
+	}
+	opts := AnomalyOptions{Kinds: []AnomalyKind{AnomalyDay}, Factor: 3, Window: 14}
+
+	below := append(slices.Clone(records), parser.Record{
+		Time: time.Date(2026, 2, 6, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Input: 1_250_000,
+	})
+	if got := FindAnomalies(below, opts); len(got) != 0 {
+		t.Errorf("expected $1.25 to stay under the floored threshold, got %+v", got)
+	}
+
+	above := append(slices.Clone(records), parser.Record{
+		Time: time.Date(2026, 2, 6, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "shop", Input: 1_350_000,
+	})
+	got := FindAnomalies(above, opts)
+	if len(got) != 1 || got[0].MAD != 0 || !almostEqual(got[0].Threshold, 1.3) {
+		t.Fatalf("expected $1.35 flagged against a $1.30 threshold, got %+v", got)
+	}
+	if !almostEqual(Threshold(1, 0, 3), 1.3) || !almostEqual(Threshold(1, 1, 3), 1+3*1.4826) {
+		t.Errorf("unexpected thresholds %v and %v", Threshold(1, 0, 3), Threshold(1, 1, 3))
+	}
+}
+
+func TestComputeRates(t *testing.T) {
+	rpt := Report{
+		Rows: []Row{
+			{Key: "2026-02-14", Model: "claude-opus-4-6", Input: 1000, Output: 3000, Cost: 3, Active: 30 * time.Minute},
+			{Key: "2026-02-14", Model: "claude-haiku-4-5", Input: 2000, Output: 1000, Cost: 1},
+			{Key: "2026-02-15", Model: "claude-opus-4-6", Input: 500, Output: 500, Cost: -1, Active: time.Hour},
+		},
+		Total: Row{Key: "TOTAL", Input: 3500, Output: 4500, Cost: -1, Active: 90 * time.Minute},
+	}
+	rpt.ComputeRates()
+
+	// The first per-model row carries the rates of its whole key.
+	if r := &rpt.Rows[0]; !almostEqual(r.CostPerHour, 8) || !almostEqual(r.TokensPerMinute, 7000.0/30) || !almostEqual(r.OutputRatio, 3) {
+		t.Errorf("unexpected rates for the first row: %+v", *r)
+	}
+	if r := &rpt.Rows[1]; r.CostPerHour != 0 || r.TokensPerMinute != 0 || !almostEqual(r.OutputRatio, 0.5) {
+		t.Errorf("expected only an output ratio on the second model row: %+v", *r)
+	}
+	if r := &rpt.Rows[2]; r.CostPerHour != -1 || !almostEqual(r.TokensPerMinute, 1000.0/60) {
+		t.Errorf("expected unknown cost per hour: %+v", *r)
+	}
+	if r := &rpt.Total; r.CostPerHour != -1 || !almostEqual(r.OutputRatio, 4500.0/3500) {
+		t.Errorf("unexpected total rates: %+v", *r)
+	}
+}
+
+func TestByUser(t *testing.T) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Session: "s1", Input: 100, User: "alice"},
+		{Time: time.Date(2026, 2, 1, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Session: "s2", Input: 200, User: "bob"},
+		{Time: time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Session: "s3", Input: 400},
+	}
+	sessions := []parser.Session{{ID: "s2", Date: "2026-02-01", Duration: time.Hour, User: "bob"}}
+
+	rpt := By(User, records, sessions, false)
+	if len(rpt.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", rpt.Rows)
+	}
+	for i, want := range []string{"(local)", "alice", "bob"} {
+		if rpt.Rows[i].Key != want {
+			t.Errorf("row %d: expected %q, got %q", i, want, rpt.Rows[i].Key)
+		}
+	}
+	if rpt.Rows[2].Duration != time.Hour || rpt.Rows[2].Input != 200 {
+		t.Errorf("unexpected bob row %+v", rpt.Rows[2])
+	}
+}
@@ -0,0 +1,163 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortField selects the value report rows are ordered by.
+type SortField string
+
+const (
+	SortKey      SortField = "key"
+	SortCost     SortField = "cost"
+	SortTokens   SortField = "tokens"
+	SortDuration SortField = "duration"
+)
+
+// ParseSortField validates a sort field name.
+func ParseSortField(s string) (SortField, error) {
+	switch f := SortField(strings.ToLower(s)); f {
+	case SortKey, SortCost, SortTokens, SortDuration:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown sort %q (want key, cost, tokens or duration)", s)
+	}
+}
+
+// OtherKey labels the row that Top folds the remaining groups into.
+const OtherKey = "(other)"
+
+// Add accumulates o into r. Cost becomes -1 if either is unknown; Agents is
+// summed, so a subagent spanning both rows is counted twice.
+func (r *Row) Add(o *Row) {
+	r.Input += o.Input
+	r.Output += o.Output
+	r.CacheWrite += o.CacheWrite
+	r.CacheRead += o.CacheRead
+	if r.Cost < 0 || o.Cost < 0 {
+		r.Cost = -1
+	} else {
+		r.Cost += o.Cost
+	}
+	r.Duration += o.Duration
+	r.Active += o.Active
+	r.WebSearches += o.WebSearches
+	r.WebFetches += o.WebFetches
+	r.ServerToolCost += o.ServerToolCost
+	r.CacheSaved += o.CacheSaved
+	r.CacheWritePremium += o.CacheWritePremium
+	r.AgentCost += o.AgentCost
+	r.Agents += o.Agents
+	r.ToolCalls += o.ToolCalls
+}
+
+// group is a run of rows sharing a key: one row, or one per model in
+// detailed reports.
+type group struct {
+	rows []Row
+	sum  Row
+}
+
+func (r *Report) groups() []group {
+	var gs []group
+	for i := range r.Rows {
+		row := &r.Rows[i]
+		if len(gs) == 0 || gs[len(gs)-1].rows[0].Key != row.Key {
+			gs = append(gs, group{sum: Row{Key: row.Key}})
+		}
+		g := &gs[len(gs)-1]
+		g.rows = append(g.rows, *row)
+		g.sum.Add(row)
+	}
+	return gs
+}
+
+func (r *Report) setGroups(gs []group) {
+	rows := make([]Row, 0, len(r.Rows))
+	for i := range gs {
+		rows = append(rows, gs[i].rows...)
+	}
+	r.Rows = rows
+}
+
+// compareBy orders group totals by field, ascending. Unknown costs sort as
+// zero. SortKey compares keys; other fields report ties as 0.
+func compareBy(field SortField, a, b *Row) int {
+	switch field {
+	case SortCost:
+		return cmp.Compare(max(a.Cost, 0), max(b.Cost, 0))
+	case SortTokens:
+		return cmp.Compare(a.Input+a.Output+a.CacheWrite+a.CacheRead, b.Input+b.Output+b.CacheWrite+b.CacheRead)
+	case SortDuration:
+		return cmp.Compare(a.Duration, b.Duration)
+	default:
+		return cmp.Compare(a.Key, b.Key)
+	}
+}
+
+// Sort orders rows by field, descending when desc is set; ties are broken
+// by key, ascending. In detailed reports the per-model rows of a key stay
+// together, ordered by the key's total, and keep their model order.
+func (r *Report) Sort(field SortField, desc bool) {
+	gs := r.groups()
+	slices.SortStableFunc(gs, func(a, b group) int {
+		c := compareBy(field, &a.sum, &b.sum)
+		if desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		return cmp.Compare(a.sum.Key, b.sum.Key)
+	})
+	r.setGroups(gs)
+
+	for i := range r.Rows {
+		if len(r.Rows[i].Children) > 0 {
+			sub := Report{Rows: r.Rows[i].Children}
+			sub.Sort(field, desc)
+			r.Rows[i].Children = sub.Rows
+		}
+	}
+}
+
+// Top keeps the n keys with the largest field value (cost when field is
+// SortKey) in their current order and folds the rest into a single row
+// keyed OtherKey, appended last. The total is unchanged. In nested reports
+// only the outermost level is cut.
+func (r *Report) Top(n int, field SortField) {
+	gs := r.groups()
+	if n <= 0 || len(gs) <= n {
+		return
+	}
+	if field == SortKey {
+		field = SortCost
+	}
+
+	ranked := make([]int, len(gs))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	slices.SortStableFunc(ranked, func(a, b int) int {
+		return compareBy(field, &gs[b].sum, &gs[a].sum)
+	})
+	keep := map[int]bool{}
+	for _, i := range ranked[:n] {
+		keep[i] = true
+	}
+
+	other := Row{Key: OtherKey}
+	kept := make([]group, 0, n+1)
+	for i := range gs {
+		if keep[i] {
+			kept = append(kept, gs[i])
+		} else {
+			other.Add(&gs[i].sum)
+		}
+	}
+	kept = append(kept, group{rows: []Row{other}})
+	r.setGroups(kept)
+}
@@ -0,0 +1,299 @@
+// Package store writes usage records to a SQLite database for ad-hoc SQL
+// analysis. It uses a pure-Go driver, so builds need no cgo.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/pricing"
+	"github.com/zulerne/ccost/internal/report"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// SchemaVersion is stored in PRAGMA user_version. Databases written by a
+// newer version are refused rather than appended to; older ones are
+// migrated. Version 2 keys sessions by branch as well as day.
+const SchemaVersion = 2
+
+const schema = `
+CREATE TABLE IF NOT EXISTS projects (
+	id   INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	repo TEXT NOT NULL,
+	cwd  TEXT NOT NULL,
+	UNIQUE (name, cwd)
+);
+CREATE TABLE IF NOT EXISTS sessions (
+	id               TEXT NOT NULL,
+	date             TEXT NOT NULL,
+	project_id       INTEGER NOT NULL REFERENCES projects (id),
+	branch           TEXT NOT NULL,
+	duration_seconds INTEGER NOT NULL,
+	active_seconds   INTEGER NOT NULL,
+	PRIMARY KEY (id, date, branch)
+);
+DROP TABLE IF EXISTS models;
+CREATE TABLE models (
+	name                   TEXT PRIMARY KEY,
+	input_per_mtok         REAL NOT NULL,
+	output_per_mtok        REAL NOT NULL,
+	cache_write_per_mtok   REAL NOT NULL,
+	cache_read_per_mtok    REAL NOT NULL,
+	web_search_per_request REAL NOT NULL,
+	web_fetch_per_request  REAL NOT NULL
+);
+CREATE TABLE IF NOT EXISTS records (
+	message_id          TEXT PRIMARY KEY,
+	time                TEXT NOT NULL,
+	project_id          INTEGER NOT NULL REFERENCES projects (id),
+	session_id          TEXT NOT NULL,
+	agent_id            TEXT,
+	branch              TEXT NOT NULL,
+	model               TEXT NOT NULL,
+	input_tokens        INTEGER NOT NULL,
+	output_tokens       INTEGER NOT NULL,
+	cache_write_tokens  INTEGER NOT NULL,
+	cache_read_tokens   INTEGER NOT NULL,
+	web_search_requests INTEGER NOT NULL,
+	web_fetch_requests  INTEGER NOT NULL,
+	cost                REAL
+);
+CREATE INDEX IF NOT EXISTS records_time ON records (time);
+CREATE INDEX IF NOT EXISTS records_session ON records (session_id);
+CREATE TABLE IF NOT EXISTS tool_calls (
+	message_id TEXT NOT NULL REFERENCES records (message_id),
+	tool       TEXT NOT NULL,
+	calls      INTEGER NOT NULL,
+	PRIMARY KEY (message_id, tool)
+);
+`
+
+// Stats counts what a Write call added.
+type Stats struct {
+	Records  int // new records
+	Updated  int // records that had grown since they were written
+	Skipped  int // records already in the database, unchanged
+	Sessions int // session days inserted or extended
+}
+
+// Write appends records and sessions to the SQLite database at path,
+// creating it if needed, and rebuilds the models table with the current
+// prices. Records already present (by message ID) are replaced only when
+// they have more output tokens, as the parser does for a message logged
+// while it was still streaming, so running it again over overlapping
+// ranges only adds or completes what changed. Session
+// days keep the longest duration seen per branch, since a day in progress
+// grows between runs. Everything is written in one transaction.
+func Write(path string, records []parser.Record, sessions []parser.Session) (Stats, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return Stats{}, fmt.Errorf("opening database: %w", err)
+	}
+	st, err := write(context.Background(), db, records, sessions)
+	return st, errors.Join(err, db.Close())
+}
+
+func write(ctx context.Context, db *sql.DB, records []parser.Record, sessions []parser.Session) (Stats, error) {
+	var st Stats
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return st, fmt.Errorf("reading schema version: %w", err)
+	}
+	if version > SchemaVersion {
+		return st, fmt.Errorf("database schema version %d is newer than supported %d", version, SchemaVersion)
+	}
+
+	tx, txErr := db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return st, fmt.Errorf("starting transaction: %w", txErr)
+	}
+	defer func() { _ = tx.Rollback() }() // no-op after Commit
+
+	// Version 1 had one session row per day; move its rows to the new table.
+	if version == 1 {
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE sessions RENAME TO sessions_v1`); err != nil {
+			return st, fmt.Errorf("migrating sessions: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, schema); err != nil {
+		return st, fmt.Errorf("creating schema: %w", err)
+	}
+	if version == 1 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO sessions SELECT * FROM sessions_v1; DROP TABLE sessions_v1`); err != nil {
+			return st, fmt.Errorf("migrating sessions: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", SchemaVersion)); err != nil {
+		return st, fmt.Errorf("setting schema version: %w", err)
+	}
+	if err := writeModels(ctx, tx); err != nil {
+		return st, err
+	}
+
+	w := writer{ctx: ctx, tx: tx, projects: map[[2]string]int64{}}
+	for i := range sessions {
+		n, err := w.session(&sessions[i])
+		if err != nil {
+			return st, err
+		}
+		st.Sessions += n
+	}
+	for i := range records {
+		res, err := w.record(&records[i])
+		if err != nil {
+			return st, err
+		}
+		switch res {
+		case inserted:
+			st.Records++
+		case updated:
+			st.Updated++
+		default:
+			st.Skipped++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return st, fmt.Errorf("committing: %w", err)
+	}
+	return st, nil
+}
+
+func writeModels(ctx context.Context, tx *sql.Tx) error {
+	for _, name := range pricing.Models() {
+		p, _ := pricing.Lookup(name)
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO models VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			name, p.Input, p.Output, p.CacheWrite, p.CacheRead,
+			pricing.WebSearchPrice, pricing.WebFetchPrice); err != nil {
+			return fmt.Errorf("writing models: %w", err)
+		}
+	}
+	return nil
+}
+
+type writer struct {
+	ctx      context.Context
+	tx       *sql.Tx
+	projects map[[2]string]int64 // name and cwd → projects.id
+}
+
+// project returns the ID of the project row for name and cwd, inserting it
+// on first use.
+func (w *writer) project(name, repo, cwd string) (int64, error) {
+	key := [2]string{name, cwd}
+	if id, ok := w.projects[key]; ok {
+		return id, nil
+	}
+	var id int64
+	err := w.tx.QueryRowContext(w.ctx,
+		`INSERT INTO projects (name, repo, cwd) VALUES (?, ?, ?)
+		 ON CONFLICT (name, cwd) DO UPDATE SET repo = excluded.repo
+		 RETURNING id`,
+		name, repo, cwd).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("writing project %q: %w", name, err)
+	}
+	w.projects[key] = id
+	return id, nil
+}
+
+func (w *writer) session(s *parser.Session) (int, error) {
+	pid, err := w.project(s.Project, s.Repo, s.CWD)
+	if err != nil {
+		return 0, err
+	}
+	res, err := w.tx.ExecContext(w.ctx,
+		`INSERT INTO sessions VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (id, date, branch) DO UPDATE SET
+			duration_seconds = excluded.duration_seconds,
+			active_seconds = excluded.active_seconds
+		 WHERE excluded.duration_seconds > sessions.duration_seconds`,
+		s.ID, s.Date, pid, s.Branch, int64(s.Duration/time.Second), int64(s.Active/time.Second))
+	if err != nil {
+		return 0, fmt.Errorf("writing session %s: %w", s.ID, err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// recordResult says what writing a record changed.
+type recordResult int
+
+const (
+	unchanged recordResult = iota
+	inserted
+	updated
+)
+
+// record inserts r and its tool calls. A record with the same message ID is
+// replaced, tool calls included, only if r has more output tokens.
+func (w *writer) record(r *parser.Record) (recordResult, error) {
+	pid, err := w.project(r.Project, r.Repo, r.CWD)
+	if err != nil {
+		return unchanged, err
+	}
+	var cost, agent any
+	if c := report.RecordCost(r); c >= 0 {
+		cost = c
+	}
+	if r.AgentID != "" {
+		agent = r.AgentID
+	}
+	var exists bool
+	if err = w.tx.QueryRowContext(w.ctx,
+		`SELECT EXISTS (SELECT 1 FROM records WHERE message_id = ?)`, r.ID).Scan(&exists); err != nil {
+		return unchanged, fmt.Errorf("reading record %s: %w", r.ID, err)
+	}
+	res, err := w.tx.ExecContext(w.ctx,
+		`INSERT INTO records VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (message_id) DO UPDATE SET
+			time = excluded.time,
+			project_id = excluded.project_id,
+			session_id = excluded.session_id,
+			agent_id = excluded.agent_id,
+			branch = excluded.branch,
+			model = excluded.model,
+			input_tokens = excluded.input_tokens,
+			output_tokens = excluded.output_tokens,
+			cache_write_tokens = excluded.cache_write_tokens,
+			cache_read_tokens = excluded.cache_read_tokens,
+			web_search_requests = excluded.web_search_requests,
+			web_fetch_requests = excluded.web_fetch_requests,
+			cost = excluded.cost
+		 WHERE excluded.output_tokens > records.output_tokens`,
+		r.ID, r.Time.UTC().Format(time.RFC3339Nano), pid, r.Session, agent, r.Branch, r.Model,
+		r.Input, r.Output, r.CacheWrite, r.CacheRead, r.WebSearches, r.WebFetches, cost)
+	if err != nil {
+		return unchanged, fmt.Errorf("writing record %s: %w", r.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return unchanged, err
+	}
+
+	result := inserted
+	if exists {
+		result = updated
+		if _, err := w.tx.ExecContext(w.ctx,
+			`DELETE FROM tool_calls WHERE message_id = ?`, r.ID); err != nil {
+			return unchanged, fmt.Errorf("writing tool calls of %s: %w", r.ID, err)
+		}
+	}
+	calls := map[string]int{}
+	for _, t := range r.Tools {
+		calls[t]++
+	}
+	for tool, n := range calls {
+		if _, err := w.tx.ExecContext(w.ctx,
+			`INSERT INTO tool_calls VALUES (?, ?, ?)`, r.ID, tool, n); err != nil {
+			return unchanged, fmt.Errorf("writing tool calls of %s: %w", r.ID, err)
+		}
+	}
+	return result, nil
+}
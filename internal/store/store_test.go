@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zulerne/ccost/internal/parser"
+)
+
+func TestWriteIncremental(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.db")
+	at := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	records := []parser.Record{
+		{ID: "m1", Time: at, Model: "claude-haiku-4-5", Project: "shop", Repo: "shop", CWD: "/src/shop", Session: "s1", Input: 1_000_000, Tools: []string{"Bash", "Bash", "Edit"}},
+		{ID: "m2", Time: at.Add(time.Minute), Model: "mystery-model", Project: "shop", Repo: "shop", CWD: "/src/shop", Session: "s1", AgentID: "agent-x", Input: 10},
+		{ID: "m3", Time: at.Add(time.Hour), Model: "claude-haiku-4-5", Project: "blog", CWD: "/src/blog", Session: "s2", Output: 1000},
+	}
+	sessions := []parser.Session{{ID: "s1", Date: "2026-02-01", Project: "shop", Repo: "shop", CWD: "/src/shop", Duration: 30 * time.Minute, Active: 20 * time.Minute}}
+
+	st, err := Write(path, records[:2], sessions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Records != 2 || st.Skipped != 0 || st.Sessions != 1 {
+		t.Errorf("unexpected first write stats %+v", st)
+	}
+
+	// A later run over an overlapping range: one new record, a longer day.
+	sessions[0].Duration = time.Hour
+	if st, err = Write(path, records, sessions); err != nil {
+		t.Fatal(err)
+	}
+	if st.Records != 1 || st.Skipped != 2 || st.Sessions != 1 {
+		t.Errorf("unexpected second write stats %+v", st)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	query := func(q string, dest ...any) {
+		t.Helper()
+		if err := db.QueryRowContext(ctx, q).Scan(dest...); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	var records3, projects, unknown int
+	var cost float64
+	query(`SELECT count(*), count(DISTINCT project_id), count(*) - count(cost), sum(cost) FROM records`, &records3, &projects, &unknown, &cost)
+	if records3 != 3 || projects != 2 || unknown != 1 || cost != 1.005 {
+		t.Errorf("unexpected records: %d rows, %d projects, %d unknown cost, $%v", records3, projects, unknown, cost)
+	}
+	var bash int
+	query(`SELECT calls FROM tool_calls WHERE message_id = 'm1' AND tool = 'Bash'`, &bash)
+	if bash != 2 {
+		t.Errorf("expected 2 Bash calls, got %d", bash)
+	}
+	var duration int
+	query(`SELECT duration_seconds FROM sessions WHERE id = 's1'`, &duration)
+	if duration != 3600 {
+		t.Errorf("expected the session day extended to 3600s, got %d", duration)
+	}
+	var input float64
+	query(`SELECT input_per_mtok FROM models WHERE name = 'claude-haiku-4-5'`, &input)
+	if input != 1 {
+		t.Errorf("expected haiku input price 1, got %v", input)
+	}
+
+	if _, err := db.ExecContext(ctx, "PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Write(path, records, sessions); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("expected a newer schema to be refused, got %v", err)
+	}
+}
+
+func TestWriteMigratesSessionsByBranch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	// A version 1 database: one session row per day.
+	if _, err := db.ExecContext(ctx, `
+CREATE TABLE projects (id INTEGER PRIMARY KEY, name TEXT NOT NULL, repo TEXT NOT NULL, cwd TEXT NOT NULL, UNIQUE (name, cwd));
+CREATE TABLE sessions (id TEXT NOT NULL, date TEXT NOT NULL, project_id INTEGER NOT NULL REFERENCES projects (id), branch TEXT NOT NULL,
+	duration_seconds INTEGER NOT NULL, active_seconds INTEGER NOT NULL, PRIMARY KEY (id, date));
+INSERT INTO projects VALUES (1, 'shop', 'shop', '/src/shop');
+INSERT INTO sessions VALUES ('s1', '2026-02-01', 1, 'main', 600, 300);
+PRAGMA user_version = 1;`); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := []parser.Session{{ID: "s1", Date: "2026-02-01", Project: "shop", Repo: "shop", CWD: "/src/shop", Branch: "feat", Duration: time.Hour}}
+	if _, err := Write(path, nil, sessions); err != nil {
+		t.Fatal(err)
+	}
+
+	var version, rows, total int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT count(*), sum(duration_seconds) FROM sessions WHERE id = 's1'`).Scan(&rows, &total); err != nil {
+		t.Fatal(err)
+	}
+	if version != SchemaVersion || rows != 2 || total != 4200 {
+		t.Errorf("expected version %d with main and feat rows (4200s), got version %d, %d rows, %ds", SchemaVersion, version, rows, total)
+	}
+}
+
+func TestWriteCompletesStreamedRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.db")
+	at := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	partial := parser.Record{ID: "m1", Time: at, Model: "claude-haiku-4-5", Project: "shop", CWD: "/src/shop", Session: "s1", Input: 1000, Output: 10, Tools: []string{"Bash"}}
+	full := partial
+	full.Output, full.WebSearches, full.Tools = 500, 2, []string{"Bash", "Edit"}
+
+	if _, err := Write(path, []parser.Record{partial}, nil); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Write(path, []parser.Record{full}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Records != 0 || st.Updated != 1 || st.Skipped != 0 {
+		t.Errorf("expected the record to be updated, got %+v", st)
+	}
+	if st, err = Write(path, []parser.Record{partial}, nil); err != nil || st.Updated != 0 || st.Skipped != 1 {
+		t.Errorf("expected a smaller record to be skipped, got %+v, %v", st, err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	var output, tools int
+	var cost, recomputed float64
+	if err := db.QueryRowContext(context.Background(), `
+SELECT r.output_tokens, (SELECT count(*) FROM tool_calls t WHERE t.message_id = r.message_id), r.cost,
+	(r.input_tokens * m.input_per_mtok + r.output_tokens * m.output_per_mtok +
+	 r.cache_write_tokens * m.cache_write_per_mtok + r.cache_read_tokens * m.cache_read_per_mtok) / 1e6 +
+	r.web_search_requests * m.web_search_per_request + r.web_fetch_requests * m.web_fetch_per_request
+FROM records r JOIN models m ON m.name = r.model`).Scan(&output, &tools, &cost, &recomputed); err != nil {
+		t.Fatal(err)
+	}
+	if output != 500 || tools != 2 {
+		t.Errorf("expected the complete record with 2 tools, got %d output tokens and %d tools", output, tools)
+	}
+	if math.Abs(cost-recomputed) > 1e-9 || cost < 0.02 {
+		t.Errorf("expected cost %v to be recomputable from models, got %v", cost, recomputed)
+	}
+}
@@ -0,0 +1,390 @@
+// Package tui implements an interactive full-screen browser over parsed
+// usage records. All views are computed in memory from the records loaded at
+// startup; logs are never re-parsed.
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/zulerne/ccost/internal/display"
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+// Options configures the browser.
+type Options struct {
+	Title    string         // describes the loaded period
+	Location *time.Location // zone used for relative date ranges
+	Exact    bool           // start with exact token counts
+}
+
+// Run starts the browser and blocks until the user quits.
+func Run(records []parser.Record, sessions []parser.Session, opts Options) error {
+	m := newModel(records, sessions, opts, time.Now())
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("running TUI: %w", err)
+	}
+	return nil
+}
+
+// span is a date range preset relative to today.
+type span struct {
+	label string
+	days  int // 0 means every loaded record
+}
+
+var spans = []span{
+	{"all loaded", 0},
+	{"last 7 days", 7},
+	{"last 30 days", 30},
+	{"last 90 days", 90},
+}
+
+// columns are the sortable table columns, in display order.
+var columns = []string{"Key", "Input", "Output", "Write", "Read", "Time", "Cost"}
+
+// dimKeys maps keystrokes to groupings.
+var dimKeys = map[string]report.Dimension{
+	"d": report.Date,
+	"p": report.Project,
+	"r": report.Repo,
+	"b": report.Branch,
+	"m": report.Model,
+	"s": report.Session,
+	"a": report.Agent,
+}
+
+// drillOrder is the preferred next grouping when drilling into a row.
+var drillOrder = []report.Dimension{report.Date, report.Project, report.Session, report.Model}
+
+// level is one step of the drill-down stack. The root level has no filter.
+type level struct {
+	dim       report.Dimension
+	filterDim report.Dimension
+	filterKey string
+	cursor    int
+	offset    int
+}
+
+type model struct {
+	records  []parser.Record
+	sessions []parser.Session
+	opts     Options
+	now      time.Time
+
+	stack    []level
+	span     int
+	sortCol  int
+	sortDesc bool
+	exact    bool
+
+	width, height int
+
+	rpt report.Report // current view, recomputed on every state change
+}
+
+func newModel(records []parser.Record, sessions []parser.Session, opts Options, now time.Time) *model {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+	m := &model{
+		records:  records,
+		sessions: sessions,
+		opts:     opts,
+		now:      now.In(opts.Location),
+		stack:    []level{{dim: report.Date}},
+		exact:    opts.Exact,
+		width:    120,
+		height:   30,
+	}
+	m.refresh()
+	return m
+}
+
+func (m *model) top() *level {
+	return &m.stack[len(m.stack)-1]
+}
+
+// refresh recomputes the report for the current range, drill filters and
+// grouping, then sorts it and clamps the cursor.
+func (m *model) refresh() {
+	var since string
+	if days := spans[m.span].days; days > 0 {
+		since = m.now.AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+	}
+
+	var records []parser.Record
+	for i := range m.records {
+		r := &m.records[i]
+		if since != "" && r.Time.Format("2006-01-02") < since {
+			continue
+		}
+		if m.matches(func(d report.Dimension) string { return d.RecordKey(r) }) {
+			records = append(records, *r)
+		}
+	}
+	var sessions []parser.Session
+	for i := range m.sessions {
+		s := &m.sessions[i]
+		if since != "" && s.Date < since {
+			continue
+		}
+		if m.matches(func(d report.Dimension) string { return d.SessionKey(s) }) {
+			sessions = append(sessions, *s)
+		}
+	}
+
+	m.rpt = report.By(m.top().dim, records, sessions, false)
+	m.sortRows()
+
+	lv := m.top()
+	lv.cursor = max(0, min(lv.cursor, len(m.rpt.Rows)-1))
+}
+
+// matches reports whether an item passes every drill filter on the stack.
+func (m *model) matches(key func(report.Dimension) string) bool {
+	for _, lv := range m.stack[1:] {
+		if key(lv.filterDim) != lv.filterKey {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *model) sortRows() {
+	less := func(a, b report.Row) int {
+		switch m.sortCol {
+		case 1:
+			return cmp.Compare(a.Input, b.Input)
+		case 2:
+			return cmp.Compare(a.Output, b.Output)
+		case 3:
+			return cmp.Compare(a.CacheWrite, b.CacheWrite)
+		case 4:
+			return cmp.Compare(a.CacheRead, b.CacheRead)
+		case 5:
+			return cmp.Compare(a.Duration, b.Duration)
+		case 6:
+			return cmp.Compare(a.Cost, b.Cost)
+		default:
+			return 0
+		}
+	}
+	slices.SortStableFunc(m.rpt.Rows, func(a, b report.Row) int {
+		c := less(a, b)
+		if c == 0 {
+			c = cmp.Compare(a.Key, b.Key)
+		}
+		if m.sortDesc {
+			return -c
+		}
+		return c
+	})
+}
+
+// drillDim picks the next grouping when drilling into a row: the first
+// preferred dimension not already fixed by the stack.
+func (m *model) drillDim() (report.Dimension, bool) {
+	used := map[report.Dimension]bool{m.top().dim: true}
+	for _, lv := range m.stack[1:] {
+		used[lv.filterDim] = true
+	}
+	for _, d := range drillOrder {
+		if !used[d] {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+func (m *model) Init() tea.Cmd {
+	return nil
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.scroll()
+	case tea.KeyMsg:
+		cmd := m.handleKey(msg.String())
+		m.scroll()
+		return m, cmd
+	}
+	return m, nil
+}
+
+// scroll keeps the cursor row inside the visible window.
+func (m *model) scroll() {
+	lv := m.top()
+	page := m.pageSize()
+	if lv.cursor < lv.offset {
+		lv.offset = lv.cursor
+	}
+	if lv.cursor >= lv.offset+page {
+		lv.offset = lv.cursor - page + 1
+	}
+}
+
+func (m *model) handleKey(key string) tea.Cmd {
+	lv := m.top()
+	switch key {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		lv.cursor = max(0, lv.cursor-1)
+	case "down", "j":
+		lv.cursor = max(0, min(lv.cursor+1, len(m.rpt.Rows)-1))
+	case "pgup":
+		lv.cursor = max(0, lv.cursor-m.pageSize())
+	case "pgdown":
+		lv.cursor = max(0, min(lv.cursor+m.pageSize(), len(m.rpt.Rows)-1))
+	case "home", "g":
+		lv.cursor = 0
+	case "end", "G":
+		lv.cursor = max(0, len(m.rpt.Rows)-1)
+	case "enter":
+		if len(m.rpt.Rows) == 0 {
+			return nil
+		}
+		next, ok := m.drillDim()
+		if !ok {
+			return nil
+		}
+		m.stack = append(m.stack, level{dim: next, filterDim: lv.dim, filterKey: m.rpt.Rows[lv.cursor].Key})
+		m.refresh()
+	case "esc", "backspace":
+		if len(m.stack) > 1 {
+			m.stack = m.stack[:len(m.stack)-1]
+			m.refresh()
+		}
+	case "t":
+		m.span = (m.span + 1) % len(spans)
+		m.refresh()
+	case "right":
+		m.sortCol = (m.sortCol + 1) % len(columns)
+		m.refresh()
+	case "left":
+		m.sortCol = (m.sortCol + len(columns) - 1) % len(columns)
+		m.refresh()
+	case "o":
+		m.sortDesc = !m.sortDesc
+		m.refresh()
+	case "e":
+		m.exact = !m.exact
+	default:
+		if d, ok := dimKeys[key]; ok && d != lv.dim {
+			lv.dim = d
+			lv.cursor, lv.offset = 0, 0
+			m.refresh()
+		}
+	}
+	return nil
+}
+
+// pageSize is the number of data rows that fit on screen: the terminal
+// height minus the header, help line and table chrome (borders, column
+// headers and the TOTAL footer).
+func (m *model) pageSize() int {
+	return max(1, m.height-9)
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+	b.WriteString(text.FgCyan.Sprint(m.breadcrumb()))
+	b.WriteString("\n\n")
+
+	lv := m.top()
+	end := min(lv.offset+m.pageSize(), len(m.rpt.Rows))
+
+	tw := table.NewWriter()
+	header := make(table.Row, len(columns))
+	for i, c := range columns {
+		if i == 0 {
+			c = lv.dim.Header()
+		}
+		if i == m.sortCol {
+			if m.sortDesc {
+				c += " ▼"
+			} else {
+				c += " ▲"
+			}
+		}
+		header[i] = c
+	}
+	tw.AppendHeader(header)
+	for i := lv.offset; i < end; i++ {
+		tw.AppendRow(m.tableRow(&m.rpt.Rows[i]))
+	}
+	total := m.tableRow(&m.rpt.Total)
+	total[0] = fmt.Sprintf("TOTAL (%d)", len(m.rpt.Rows))
+	tw.AppendFooter(total)
+
+	var colConfigs []table.ColumnConfig
+	for i := 2; i <= len(columns); i++ {
+		colConfigs = append(colConfigs, table.ColumnConfig{
+			Number:      i,
+			Align:       text.AlignRight,
+			AlignHeader: text.AlignRight,
+			AlignFooter: text.AlignRight,
+		})
+	}
+	tw.SetColumnConfigs(colConfigs)
+	tw.SetStyle(table.StyleRounded)
+	tw.Style().Format.Header = text.FormatDefault
+	tw.Style().Format.Footer = text.FormatDefault
+	tw.Style().Color.Header = text.Colors{text.FgCyan}
+	tw.Style().Color.Footer = text.Colors{text.FgYellow}
+	tw.Style().Options.DoNotColorBordersAndSeparators = true
+
+	row := lv.offset
+	tw.SetRowPainter(func(table.Row) text.Colors {
+		defer func() { row++ }()
+		if row == lv.cursor {
+			return text.Colors{text.ReverseVideo}
+		}
+		return nil
+	})
+
+	b.WriteString(tw.Render())
+	b.WriteString("\n")
+	b.WriteString(text.Faint.Sprint(m.help()))
+	return b.String()
+}
+
+func (m *model) tableRow(r *report.Row) table.Row {
+	return table.Row{
+		r.Key,
+		display.Tokens(r.Input, m.exact),
+		display.Tokens(r.Output, m.exact),
+		display.Tokens(r.CacheWrite, m.exact),
+		display.Tokens(r.CacheRead, m.exact),
+		display.Duration(r.Duration),
+		display.Cost(r.Cost),
+	}
+}
+
+func (m *model) breadcrumb() string {
+	parts := []string{"ccost"}
+	if m.opts.Title != "" {
+		parts = append(parts, m.opts.Title)
+	}
+	parts = append(parts, spans[m.span].label)
+	for _, lv := range m.stack[1:] {
+		parts = append(parts, fmt.Sprintf("%s: %s", lv.filterDim, lv.filterKey))
+	}
+	parts = append(parts, "by "+string(m.top().dim))
+	return strings.Join(parts, " › ")
+}
+
+func (m *model) help() string {
+	const help = "d/p/r/b/m/s/a group · enter drill · esc back · t range · ←/→ sort · o reverse · e exact · q quit"
+	return text.Trim(help, m.width)
+}
@@ -0,0 +1,117 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/zulerne/ccost/internal/parser"
+	"github.com/zulerne/ccost/internal/report"
+)
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func sampleData() ([]parser.Record, []parser.Session) {
+	records := []parser.Record{
+		{Time: time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "shop", Session: "s1", Input: 1000, Output: 100},
+		{Time: time.Date(2026, 2, 14, 10, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "shop", Session: "s2", Input: 2000, Output: 200},
+		{Time: time.Date(2026, 2, 14, 11, 0, 0, 0, time.UTC), Model: "claude-haiku-4-5", Project: "blog", Session: "s3", Input: 500, Output: 50},
+		{Time: time.Date(2026, 2, 15, 9, 0, 0, 0, time.UTC), Model: "claude-opus-4-6", Project: "blog", Session: "s4", Input: 4000, Output: 400},
+	}
+	sessions := []parser.Session{
+		{ID: "s2", Date: "2026-02-14", Project: "shop", Duration: time.Hour},
+		{ID: "s3", Date: "2026-02-14", Project: "blog", Duration: 30 * time.Minute},
+	}
+	return records, sessions
+}
+
+func press(m *model, keys ...string) {
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "right":
+			msg = tea.KeyMsg{Type: tea.KeyRight}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m.Update(msg)
+	}
+}
+
+func keys(rpt *report.Report) []string {
+	var out []string //nolint:prealloc // test helper
+	for _, r := range rpt.Rows {
+		out = append(out, r.Key)
+	}
+	return out
+}
+
+func TestGroupingAndDrill(t *testing.T) {
+	records, sessions := sampleData()
+	m := newModel(records, sessions, Options{Location: time.UTC}, time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC))
+
+	if got := strings.Join(keys(&m.rpt), ","); got != "2026-02-01,2026-02-14,2026-02-15" {
+		t.Fatalf("expected date rows, got %s", got)
+	}
+
+	// Drill into 2026-02-14: grouped by project, filtered to that day.
+	press(m, "down", "enter")
+	if got := strings.Join(keys(&m.rpt), ","); got != "blog,shop" {
+		t.Fatalf("expected projects of 2026-02-14, got %s", got)
+	}
+	if m.rpt.Total.Input != 2500 {
+		t.Errorf("expected drilled total input 2500, got %d", m.rpt.Total.Input)
+	}
+	if m.rpt.Total.Duration != 90*time.Minute {
+		t.Errorf("expected drilled duration 1h30m, got %v", m.rpt.Total.Duration)
+	}
+
+	// Drill into blog on that day: grouped by session.
+	press(m, "enter")
+	if got := strings.Join(keys(&m.rpt), ","); got != "s3" {
+		t.Fatalf("expected session s3, got %s", got)
+	}
+
+	press(m, "esc", "esc")
+	if len(m.stack) != 1 || len(m.rpt.Rows) != 3 {
+		t.Fatalf("expected to be back at the root with 3 rows, got depth %d and %d rows", len(m.stack), len(m.rpt.Rows))
+	}
+
+	press(m, "m")
+	if got := strings.Join(keys(&m.rpt), ","); got != "claude-haiku-4-5,claude-opus-4-6" {
+		t.Errorf("expected model rows, got %s", got)
+	}
+}
+
+func TestRangeAndSort(t *testing.T) {
+	records, sessions := sampleData()
+	m := newModel(records, sessions, Options{Location: time.UTC}, time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC))
+
+	press(m, "p")
+	// Last 7 days drops the 2026-02-01 shop record.
+	press(m, "t")
+	if m.rpt.Total.Input != 6500 {
+		t.Errorf("expected 7-day total input 6500, got %d", m.rpt.Total.Input)
+	}
+
+	// Sort by Input descending.
+	press(m, "right", "o")
+	if got := strings.Join(keys(&m.rpt), ","); got != "blog,shop" {
+		t.Errorf("expected blog before shop by input desc, got %s", got)
+	}
+
+	view := ansiRe.ReplaceAllString(m.View(), "")
+	for _, want := range []string{"last 7 days", "by project", "Input ▼", "TOTAL (2)"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in view:\n%s", want, view)
+		}
+	}
+}